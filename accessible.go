@@ -0,0 +1,119 @@
+package fang
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// accessibleHelpFn writes the help for the given command as plain, linear
+// text, with explicit labels instead of column alignment.
+func accessibleHelpFn(c *cobra.Command, w io.Writer) {
+	if longShort := cmp.Or(c.Long, c.Short); longShort != "" {
+		_, _ = fmt.Fprintln(w, longShort)
+		_, _ = fmt.Fprintln(w)
+	}
+	if isDestructive(c) {
+		_, _ = fmt.Fprintln(w, "Destructive: asks for a confirmation, unless --yes is set.")
+		_, _ = fmt.Fprintln(w)
+	}
+
+	_, _ = fmt.Fprintln(w, "Usage: "+styleUsage(c, Program{}, true))
+
+	if example := strings.Trim(c.Example, "\n"); strings.TrimSpace(example) != "" {
+		_, _ = fmt.Fprintln(w)
+		_, _ = fmt.Fprintln(w, "Examples:")
+		for _, line := range strings.Split(example, "\n") {
+			_, _ = fmt.Fprintln(w, strings.TrimSpace(line))
+		}
+	}
+
+	groups, groupIDs := evalGroups(c)
+	for _, groupID := range groupIDs {
+		var lines []string
+		for _, sc := range c.Commands() {
+			if sc.Hidden || sc.GroupID != groupID {
+				continue
+			}
+			line := "Command: " + styleUsage(sc, Program{}, false)
+			if sc.Short != "" {
+				line += ": " + punctuate(sc.Short)
+			}
+			if isDestructive(sc) {
+				line += " Destructive."
+			}
+			lines = append(lines, line)
+		}
+		writeAccessibleSection(w, groups[groupID], lines)
+	}
+
+	var lines []string
+	c.Flags().VisitAll(func(f *pflag.Flag) {
+		if f.Hidden {
+			return
+		}
+		name := "--" + f.Name
+		if f.Shorthand != "" {
+			name = "-" + f.Shorthand + ", " + name
+		}
+		line := "Flag: " + name
+		if usage := strings.Join(strings.Fields(f.Usage), " "); usage != "" {
+			line += ": " + punctuate(usage)
+		}
+		// values are never punctuated, so they can be copied as they are.
+		var values []string
+		switch {
+		case hasDefault(f) && isSensitive(f):
+			values = append(values, "default: set")
+		case hasDefault(f):
+			values = append(values, "default: "+f.DefValue)
+		}
+		if env := flagEnv(f); env != "" {
+			values = append(values, "environment: "+env)
+		}
+		if len(values) > 0 {
+			values[0] = titleFirstWord(values[0])
+			line += " " + strings.Join(values, ", ")
+		}
+		lines = append(lines, line)
+	})
+	writeAccessibleSection(w, "Flags", lines)
+
+	lines = nil
+	c.Flags().VisitAll(func(f *pflag.Flag) {
+		if env := flagEnv(f); !f.Hidden && env != "" {
+			lines = append(lines, "Variable: "+env+", sets: --"+f.Name)
+		}
+	})
+	writeAccessibleSection(w, "Environment", lines)
+
+	if url := c.Annotations[DocsURLAnnotation]; url != "" {
+		_, _ = fmt.Fprintln(w)
+		_, _ = fmt.Fprintln(w, "Learn more: "+url)
+	}
+}
+
+func writeAccessibleSection(w io.Writer, title string, lines []string) {
+	if len(lines) == 0 {
+		return
+	}
+	_, _ = fmt.Fprintln(w)
+	_, _ = fmt.Fprintln(w, titleFirstWord(title)+":")
+	for _, line := range lines {
+		_, _ = fmt.Fprintln(w, line)
+	}
+}
+
+// punctuate ends the given sentence with a period, unless it already ends
+// with a punctuation mark.
+func punctuate(s string) string {
+	s = strings.TrimSpace(s)
+	if s == "" || strings.ContainsAny(s[len(s)-1:], ".!?:") {
+		return s
+	}
+	return s + "."
+}
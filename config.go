@@ -0,0 +1,331 @@
+package fang
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"charm.land/lipgloss/v2"
+	"github.com/BurntSushi/toml"
+	"github.com/charmbracelet/colorprofile"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	configFlag     = "config"
+	showConfigFlag = "show-config"
+)
+
+// configFiles are the names of the config files looked up in the config
+// directories, in order.
+var configFiles = []string{"config.toml", "config.yaml", "config.yml", "config.json"}
+
+// errSkipRun is returned by the hooks of [beforeRun] to stop the execution
+// without an error.
+var errSkipRun = errors.New("skip run")
+
+// addConfigFlags adds the --config and --show-config flags to the given root
+// command, unless it has flags with these names already.
+func addConfigFlags(root *cobra.Command) {
+	flags := root.PersistentFlags()
+	if flags.Lookup(configFlag) == nil && root.Flags().Lookup(configFlag) == nil {
+		flags.String(configFlag, "", "Path to the config file")
+	}
+	if flags.Lookup(showConfigFlag) == nil && root.Flags().Lookup(showConfigFlag) == nil {
+		flags.Bool(showConfigFlag, false, "Print the configuration and where each value comes from")
+	}
+}
+
+// configPaths returns the paths the config file of the given program is
+// looked up in, in order: the config directory of the user, then the ones in
+// XDG_CONFIG_DIRS.
+func configPaths(root *cobra.Command) []string {
+	var dirs []string
+	if dir, err := configDir(root); err == nil {
+		dirs = append(dirs, dir)
+	}
+	for _, dir := range filepath.SplitList(cmp.Or(os.Getenv("XDG_CONFIG_DIRS"), "/etc/xdg")) {
+		if dir != "" {
+			dirs = append(dirs, filepath.Join(dir, root.Name()))
+		}
+	}
+	paths := make([]string, 0, len(dirs)*len(configFiles))
+	for _, dir := range dirs {
+		for _, name := range configFiles {
+			paths = append(paths, filepath.Join(dir, name))
+		}
+	}
+	return paths
+}
+
+// findConfig returns the path of the config file of the given command: the
+// one set with --config, or the first one found in [configPaths]. It returns
+// an empty path if there is none.
+func findConfig(c *cobra.Command) (string, bool) {
+	if f := c.Flags().Lookup(configFlag); f != nil && f.Value.String() != "" {
+		return f.Value.String(), true
+	}
+	for _, path := range configPaths(c.Root()) {
+		if _, err := os.Stat(path); err == nil {
+			return path, false
+		}
+	}
+	return "", false
+}
+
+// loadConfig reads the config file at the given path, in the format its
+// extension tells.
+func loadConfig(path string) (map[string]any, error) {
+	bts, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read config: %w", err)
+	}
+	config := map[string]any{}
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".toml":
+		err = toml.Unmarshal(bts, &config)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(bts, &config)
+	case ".json":
+		// keep the numbers as they are written, large integers would be
+		// turned into floats otherwise.
+		dec := json.NewDecoder(bytes.NewReader(bts))
+		dec.UseNumber()
+		err = dec.Decode(&config)
+	default:
+		return nil, fmt.Errorf("unsupported config format %q: use .toml, .yaml or .json", ext)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not parse config %s: %w", path, err)
+	}
+	return config, nil
+}
+
+// configLayers returns the tables of the given config applying to the given
+// command, from the root table to the one of the command, along with their
+// commands.
+func configLayers(c *cobra.Command, config map[string]any) ([]map[string]any, []*cobra.Command) {
+	var path []*cobra.Command
+	for p := c; p != nil; p = p.Parent() {
+		path = append([]*cobra.Command{p}, path...)
+	}
+	layers := []map[string]any{config}
+	cmds := []*cobra.Command{path[0]}
+	for _, p := range path[1:] {
+		table, ok := layers[len(layers)-1][p.Name()].(map[string]any)
+		if !ok {
+			break
+		}
+		layers = append(layers, table)
+		cmds = append(cmds, p)
+	}
+	return layers, cmds
+}
+
+// checkConfig returns an error if a table of the given config applying to the
+// given command has keys which are neither flags nor subcommands.
+func checkConfig(c *cobra.Command, path string, config map[string]any) error {
+	layers, cmds := configLayers(c, config)
+	for i, layer := range layers {
+		keys := make([]string, 0, len(layer))
+		for key := range layer {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if _, ok := layer[key].(map[string]any); ok && findSubCommand(cmds[i], key) != nil {
+				continue
+			}
+			if i == 0 && isThemeKey(c.Root(), key) {
+				continue
+			}
+			if _, _, err := resolveConfigKey(c.Root(), configKey(cmds[i], key)); err != nil {
+				return fmt.Errorf("invalid config %s: %w", path, err)
+			}
+		}
+	}
+	return nil
+}
+
+// configKey returns the dotted key of the given key in the table of the given
+// command, e.g. "deploy.region".
+func configKey(c *cobra.Command, key string) string {
+	var parts []string
+	for p := c; p.HasParent(); p = p.Parent() {
+		parts = append([]string{p.Name()}, parts...)
+	}
+	return strings.Join(append(parts, key), ".")
+}
+
+// configValue returns the value of the given flag in the given config layers
+// of the given commands, the deepest one winning, and its key.
+func configValue(layers []map[string]any, cmds []*cobra.Command, f *pflag.Flag) (any, string, bool) {
+	for i := len(layers) - 1; i >= 0; i-- {
+		v, ok := layers[i][f.Name]
+		if !ok {
+			continue
+		}
+		if _, table := v.(map[string]any); table {
+			continue
+		}
+		return v, configKey(cmds[i], f.Name), true
+	}
+	return nil, "", false
+}
+
+// setFlag sets the given flag of the given command to the given config
+// value.
+func setFlag(c *cobra.Command, f *pflag.Flag, v any) error {
+	list, isList := v.([]any)
+	if !isList {
+		//nolint:wrapcheck
+		return c.Flags().Set(f.Name, configScalar(v))
+	}
+	values := make([]string, 0, len(list))
+	for _, item := range list {
+		values = append(values, configScalar(item))
+	}
+	if sv, ok := f.Value.(pflag.SliceValue); ok {
+		if err := sv.Replace(values); err != nil {
+			//nolint:wrapcheck
+			return err
+		}
+		f.Changed = true
+		return nil
+	}
+	//nolint:wrapcheck
+	return c.Flags().Set(f.Name, strings.Join(values, ","))
+}
+
+// configScalar returns the given scalar config value as it would be given to
+// its flag on the command line, with floats never in exponent form, e.g. for
+// 1e+06 in YAML.
+func configScalar(v any) string {
+	if f, ok := v.(float64); ok {
+		return strconv.FormatFloat(f, 'f', -1, 64)
+	}
+	return fmt.Sprint(v)
+}
+
+// flagSources records where the values of the flags set by fang came from.
+type flagSources map[*pflag.Flag]string
+
+// applyEnv is [applyEnv], recording the variables it sets flags from.
+func (s flagSources) applyEnv(c *cobra.Command, args []string) error {
+	changed := map[*pflag.Flag]bool{}
+	c.Flags().VisitAll(func(f *pflag.Flag) { changed[f] = f.Changed })
+	if err := applyEnv(c, args); err != nil {
+		return err
+	}
+	c.Flags().VisitAll(func(f *pflag.Flag) {
+		if f.Changed && !changed[f] {
+			s[f] = "$" + flagEnv(f)
+		}
+	})
+	return nil
+}
+
+// applyConfig sets the flags of the given command not set on the command line
+// nor from the environment from the config file, if any.
+func (s flagSources) applyConfig(c *cobra.Command, _ []string) error {
+	if isConfigCommand(c) {
+		return nil
+	}
+	path, explicit := findConfig(c)
+	if path == "" {
+		return nil
+	}
+	config, err := loadConfig(path)
+	if err != nil {
+		if !explicit && errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	if err := checkConfig(c, path, config); err != nil {
+		return err
+	}
+	layers, cmds := configLayers(c, config)
+	var serr error
+	c.Flags().VisitAll(func(f *pflag.Flag) {
+		if serr != nil || f.Changed || f.Name == configFlag {
+			return
+		}
+		v, key, ok := configValue(layers, cmds, f)
+		if !ok {
+			return
+		}
+		if err := setFlag(c, f, v); err != nil {
+			serr = fmt.Errorf("invalid value for %s in %s: %w", key, path, err)
+			return
+		}
+		s[f] = "config: " + key
+	})
+	return serr
+}
+
+// showConfig prints the flags of the given command, their values and where
+// they come from, if --show-config is set, and stops the execution then.
+func (s flagSources) showConfig(styles func() Styles) func(*cobra.Command, []string) error {
+	return func(c *cobra.Command, _ []string) error {
+		if show, _ := c.Flags().GetBool(showConfigFlag); !show {
+			return nil
+		}
+		w := colorprofile.NewWriter(c.OutOrStdout(), os.Environ())
+		s.writeConfig(w, styles(), c)
+		return errSkipRun
+	}
+}
+
+func (s flagSources) writeConfig(w io.Writer, styles Styles, c *cobra.Command) {
+	path, _ := findConfig(c)
+	var keys, values []string
+	c.Flags().VisitAll(func(f *pflag.Flag) {
+		if f.Hidden || isDefaultFlag(f.Name) || f.Name == configFlag || f.Name == showConfigFlag {
+			return
+		}
+		value := f.Value.String()
+		if isSensitive(f) && value != "" {
+			value = redactedValue
+		}
+		source := s[f]
+		switch {
+		case source != "":
+		case f.Changed:
+			source = "flag"
+		default:
+			source = "default"
+		}
+		keys = append(keys, styles.Program.Flag.Render("--"+f.Name))
+		values = append(values, lipgloss.JoinHorizontal(
+			lipgloss.Left,
+			styles.FlagDescription.UnsetTransform().Render(value),
+			styles.FlagDefault.Render(" ("+source+")"),
+		))
+	})
+
+	_, _ = fmt.Fprintln(w)
+	if path == "" {
+		path = "none"
+	}
+	_, _ = fmt.Fprintln(w, strings.Repeat(" ", shortPad)+styles.Text.Render("Config file: ")+styles.Program.Argument.Render(path))
+	space := calculateSpace(keys, nil)
+	renderGroup(w, styles, space, "configuration", func(yield func(string, string) bool) {
+		for i := range keys {
+			if !yield(keys[i], values[i]) {
+				return
+			}
+		}
+	})
+	_, _ = fmt.Fprintln(w)
+}
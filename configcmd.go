@@ -0,0 +1,524 @@
+package fang
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/charmbracelet/colorprofile"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"gopkg.in/yaml.v3"
+)
+
+// configCmdAnnotation marks the commands of the `config` command, which the
+// config file doesn't apply to, so they can fix it.
+const configCmdAnnotation = "fang_config_cmd"
+
+// configKeyFlag is a flag which can be set in the config file, with its key.
+type configKeyFlag struct {
+	key  string
+	flag *pflag.Flag
+}
+
+// configKeys returns the flags of the given command tree which can be set in
+// the config file, sorted by key.
+func configKeys(root *cobra.Command) []configKeyFlag {
+	var keys []configKeyFlag
+	walk(root, func(c *cobra.Command) {
+		if !isConfigurableCommand(c) {
+			return
+		}
+		visitOwnFlags(c, func(f *pflag.Flag) {
+			if isConfigurable(f) {
+				keys = append(keys, configKeyFlag{configKey(c, f.Name), f})
+			}
+		})
+	})
+	sort.Slice(keys, func(i, j int) bool { return keys[i].key < keys[j].key })
+	return keys
+}
+
+// isConfigurable reports whether the given flag can be set in the config
+// file.
+func isConfigurable(f *pflag.Flag) bool {
+	return !f.Hidden && !isDefaultFlag(f.Name) && !isYesFlag(f) && f.Name != configFlag && f.Name != showConfigFlag
+}
+
+// isConfigCommand reports whether the given command is the `config` command
+// or one of its subcommands.
+func isConfigCommand(c *cobra.Command) bool {
+	for p := c; p != nil; p = p.Parent() {
+		if p.Annotations[configCmdAnnotation] != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// isConfigurableCommand reports whether the flags of the given command can be
+// set in the config file.
+func isConfigurableCommand(c *cobra.Command) bool {
+	for p := c; p != nil; p = p.Parent() {
+		if p.Hidden || isDefaultCommand(p) || p.Annotations[configCmdAnnotation] != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// resolveConfigKey returns the command and flag the given dotted key sets,
+// e.g. the --replicas flag of the deploy command for "deploy.replicas".
+func resolveConfigKey(root *cobra.Command, key string) (*cobra.Command, *pflag.Flag, error) {
+	parts := strings.Split(key, ".")
+	c := root
+	for _, part := range parts[:len(parts)-1] {
+		sc := findSubCommand(c, part)
+		if sc == nil || !isConfigurableCommand(sc) {
+			return nil, nil, fmt.Errorf("unknown key %q: %s has no %s command", key, c.CommandPath(), part)
+		}
+		c = sc
+	}
+	name := parts[len(parts)-1]
+	var flag *pflag.Flag
+	visitAllFlags(c, func(f *pflag.Flag) {
+		if f.Name == name && isConfigurable(f) {
+			flag = f
+		}
+	})
+	if flag == nil {
+		return nil, nil, fmt.Errorf("unknown key %q: %s has no --%s flag", key, c.CommandPath(), name)
+	}
+	return c, flag, nil
+}
+
+// parseConfigValue parses the given value of the given flag into the type to
+// write to the config file.
+func parseConfigValue(f *pflag.Flag, value string) (any, error) {
+	typ := f.Value.Type()
+	if _, ok := f.Value.(pflag.SliceValue); ok {
+		var items []any
+		if value != "" {
+			for _, item := range strings.Split(value, ",") {
+				v, err := parseScalar(strings.TrimSuffix(strings.TrimSuffix(typ, "Slice"), "Array"), strings.TrimSpace(item))
+				if err != nil {
+					return nil, err
+				}
+				items = append(items, v)
+			}
+		}
+		return items, nil
+	}
+	return parseScalar(typ, value)
+}
+
+func parseScalar(typ, value string) (any, error) {
+	var v any
+	var err error
+	switch {
+	case typ == "bool":
+		v, err = strconv.ParseBool(value)
+	case strings.HasPrefix(typ, "int") || typ == "count":
+		v, err = strconv.ParseInt(value, 10, 64)
+	case strings.HasPrefix(typ, "uint"):
+		v, err = strconv.ParseUint(value, 10, 64)
+	case strings.HasPrefix(typ, "float"):
+		v, err = strconv.ParseFloat(value, 64)
+	case typ == "duration":
+		_, err = time.ParseDuration(value)
+		v = value
+	default:
+		v = value
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s %q", typ, value)
+	}
+	return v, nil
+}
+
+// configFileFor returns the path of the config file the `config` commands
+// work on: the one in use, or config.toml in the config directory.
+func configFileFor(c *cobra.Command) (string, error) {
+	if path, _ := findConfig(c); path != "" {
+		return path, nil
+	}
+	dir, err := configDir(c.Root())
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, configFiles[0]), nil
+}
+
+// readConfig reads the config file at the given path, which may not exist
+// yet.
+func readConfig(path string) (map[string]any, error) {
+	config, err := loadConfig(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return map[string]any{}, nil
+	}
+	return config, err
+}
+
+// configComment matches the comments of TOML and YAML files, erring on the
+// side of finding some in strings.
+var configComment = regexp.MustCompile(`(?m)(^|\s)#`)
+
+// saveConfig writes the given config to the given path, in the format its
+// extension tells.
+//
+// The file is encoded again, which sorts its keys and would drop its
+// comments: files with comments are never rewritten.
+func saveConfig(path string, config map[string]any) error {
+	ext := strings.ToLower(filepath.Ext(path))
+	if bts, err := os.ReadFile(path); err == nil && ext != ".json" && configComment.Match(bts) {
+		return &Error{
+			Err:  fmt.Errorf("refusing to rewrite %s, as its comments would be lost", path),
+			Hint: "Edit the file instead.",
+		}
+	}
+	var buf bytes.Buffer
+	var err error
+	switch ext {
+	case ".toml":
+		err = toml.NewEncoder(&buf).Encode(config)
+	case ".yaml", ".yml":
+		err = yaml.NewEncoder(&buf).Encode(config)
+	case ".json":
+		enc := json.NewEncoder(&buf)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(config)
+	default:
+		return fmt.Errorf("unsupported config format %q: use .toml, .yaml or .json", filepath.Ext(path))
+	}
+	if err != nil {
+		return fmt.Errorf("could not encode config: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return fmt.Errorf("could not create config directory: %w", err)
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0o600); err != nil {
+		return fmt.Errorf("could not save config: %w", err)
+	}
+	return nil
+}
+
+// lookupConfig returns the value at the given dotted key of the given config.
+func lookupConfig(config map[string]any, key string) (any, bool) {
+	parts := strings.Split(key, ".")
+	table := config
+	for _, part := range parts[:len(parts)-1] {
+		next, ok := table[part].(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		table = next
+	}
+	v, ok := table[parts[len(parts)-1]]
+	return v, ok
+}
+
+// setConfig sets the value at the given dotted key of the given config,
+// creating the tables on the way.
+func setConfig(config map[string]any, key string, value any) {
+	parts := strings.Split(key, ".")
+	table := config
+	for _, part := range parts[:len(parts)-1] {
+		next, ok := table[part].(map[string]any)
+		if !ok {
+			next = map[string]any{}
+			table[part] = next
+		}
+		table = next
+	}
+	table[parts[len(parts)-1]] = value
+}
+
+// unsetConfig removes the value at the given dotted key of the given config,
+// and the tables left empty, and reports whether it was set.
+func unsetConfig(config map[string]any, key string) bool {
+	part, rest, nested := strings.Cut(key, ".")
+	if !nested {
+		_, ok := config[part]
+		delete(config, part)
+		return ok
+	}
+	table, ok := config[part].(map[string]any)
+	if !ok || !unsetConfig(table, rest) {
+		return false
+	}
+	if len(table) == 0 {
+		delete(config, part)
+	}
+	return true
+}
+
+// checkConfigTree returns an error if any key of the given config does not
+// set a flag.
+func checkConfigTree(root *cobra.Command, path string, config map[string]any) error {
+	var check func(prefix string, table map[string]any) error
+	check = func(prefix string, table map[string]any) error {
+		keys := make([]string, 0, len(table))
+		for key := range table {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if sub, ok := table[key].(map[string]any); ok {
+				if err := check(prefix+key+".", sub); err != nil {
+					return err
+				}
+				continue
+			}
+			if prefix == "" && isThemeKey(root, key) {
+				continue
+			}
+			if _, _, err := resolveConfigKey(root, prefix+key); err != nil {
+				return fmt.Errorf("invalid config %s: %w", path, err)
+			}
+		}
+		return nil
+	}
+	return check("", config)
+}
+
+// configValueString returns the given config value as it would be given to
+// its flag on the command line.
+func configValueString(v any) string {
+	if list, ok := v.([]any); ok {
+		items := make([]string, 0, len(list))
+		for _, item := range list {
+			items = append(items, configScalar(item))
+		}
+		return strings.Join(items, ",")
+	}
+	return configScalar(v)
+}
+
+// editor returns the command line of the editor of the user.
+func editor() []string {
+	for _, env := range []string{"VISUAL", "EDITOR"} {
+		if fields := strings.Fields(os.Getenv(env)); len(fields) > 0 {
+			return fields
+		}
+	}
+	if runtime.GOOS == "windows" {
+		return []string{"notepad"}
+	}
+	return []string{"vi"}
+}
+
+func configCmd(opts settings) *cobra.Command {
+	stylesFor := func(c *cobra.Command) Styles {
+		return opts.styles(mustColorscheme(opts.colorSchemeFor(c)))
+	}
+	keyArg := func(cmd *cobra.Command, _ []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		var keys []string
+		for _, k := range configKeys(cmd.Root()) {
+			if strings.HasPrefix(k.key, toComplete) {
+				keys = append(keys, k.key+"\t"+k.flag.Usage)
+			}
+		}
+		return keys, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "Manage the configuration",
+		Annotations: map[string]string{
+			configCmdAnnotation: "true",
+			MCPAnnotation:       MCPDeny,
+		},
+	}
+	cmd.AddCommand(
+		&cobra.Command{
+			Use:                   "list",
+			Short:                 "List the settings, their values and where they come from",
+			DisableFlagsInUseLine: true,
+			Args:                  cobra.NoArgs,
+			RunE: func(cmd *cobra.Command, _ []string) error {
+				path, err := configFileFor(cmd)
+				if err != nil {
+					return err
+				}
+				config, err := readConfig(path)
+				if err != nil {
+					return err
+				}
+				w := colorprofile.NewWriter(cmd.OutOrStdout(), os.Environ())
+				styles := stylesFor(cmd)
+				keys := configKeys(cmd.Root())
+				names := make([]string, 0, len(keys))
+				for _, k := range keys {
+					names = append(names, styles.Program.Flag.Render(k.key))
+				}
+				space := calculateSpace(names, nil)
+				_, _ = fmt.Fprintln(w)
+				_, _ = fmt.Fprintln(w, strings.Repeat(" ", shortPad)+styles.Text.Render("Config file: ")+styles.Program.Argument.Render(path))
+				renderGroup(w, styles, space, "settings", func(yield func(string, string) bool) {
+					for i, k := range keys {
+						value, source := k.flag.DefValue, "default"
+						if v, ok := lookupConfig(config, k.key); ok {
+							value, source = configValueString(v), "config"
+						}
+						if env := flagEnv(k.flag); env != "" && os.Getenv(env) != "" {
+							value, source = os.Getenv(env), "$"+env
+						}
+						if isSensitive(k.flag) && value != "" {
+							value = redactedValue
+						}
+						help := styles.FlagDescription.UnsetTransform().Render(value) +
+							styles.FlagDefault.Render(" ("+source+")")
+						if !yield(names[i], help) {
+							return
+						}
+					}
+				})
+				_, _ = fmt.Fprintln(w)
+				return nil
+			},
+		},
+		&cobra.Command{
+			Use:                   "get <key>",
+			Short:                 "Print the value of a setting",
+			DisableFlagsInUseLine: true,
+			Args:                  cobra.ExactArgs(1),
+			ValidArgsFunction:     keyArg,
+			RunE: func(cmd *cobra.Command, args []string) error {
+				_, flag, err := resolveConfigKey(cmd.Root(), args[0])
+				if err != nil {
+					return err
+				}
+				path, err := configFileFor(cmd)
+				if err != nil {
+					return err
+				}
+				config, err := readConfig(path)
+				if err != nil {
+					return err
+				}
+				value := flag.DefValue
+				if v, ok := lookupConfig(config, args[0]); ok {
+					value = configValueString(v)
+				}
+				fmt.Fprintln(cmd.OutOrStdout(), value)
+				return nil
+			},
+		},
+		&cobra.Command{
+			Use:                   "set <key> <value>",
+			Short:                 "Set the value of a setting",
+			DisableFlagsInUseLine: true,
+			Args:                  cobra.ExactArgs(2), //nolint:mnd
+			ValidArgsFunction:     keyArg,
+			RunE: func(cmd *cobra.Command, args []string) error {
+				_, flag, err := resolveConfigKey(cmd.Root(), args[0])
+				if err != nil {
+					return err
+				}
+				value, err := parseConfigValue(flag, args[1])
+				if err != nil {
+					return fmt.Errorf("invalid value for %s: %w", args[0], err)
+				}
+				path, err := configFileFor(cmd)
+				if err != nil {
+					return err
+				}
+				config, err := readConfig(path)
+				if err != nil {
+					return err
+				}
+				setConfig(config, args[0], value)
+				if err := saveConfig(path, config); err != nil {
+					return err
+				}
+				cmd.Printf("Set %s in %s\n", args[0], path)
+				return nil
+			},
+		},
+		&cobra.Command{
+			Use:                   "unset <key>",
+			Short:                 "Remove a setting, going back to its default",
+			DisableFlagsInUseLine: true,
+			Args:                  cobra.ExactArgs(1),
+			ValidArgsFunction:     keyArg,
+			RunE: func(cmd *cobra.Command, args []string) error {
+				path, err := configFileFor(cmd)
+				if err != nil {
+					return err
+				}
+				config, err := readConfig(path)
+				if err != nil {
+					return err
+				}
+				if !unsetConfig(config, args[0]) {
+					// still reject typos, but allow removing stale keys.
+					if _, _, err := resolveConfigKey(cmd.Root(), args[0]); err != nil {
+						return err
+					}
+					cmd.Printf("%s is not set in %s\n", args[0], path)
+					return nil
+				}
+				if err := saveConfig(path, config); err != nil {
+					return err
+				}
+				cmd.Printf("Unset %s in %s\n", args[0], path)
+				return nil
+			},
+		},
+		&cobra.Command{
+			Use:                   "edit",
+			Short:                 "Open the config file in your editor",
+			DisableFlagsInUseLine: true,
+			Args:                  cobra.NoArgs,
+			RunE: func(cmd *cobra.Command, _ []string) error {
+				path, err := configFileFor(cmd)
+				if err != nil {
+					return err
+				}
+				if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+					if err := saveConfig(path, map[string]any{}); err != nil {
+						return err
+					}
+				}
+				args := editor()
+				//nolint:gosec
+				editor := exec.CommandContext(cmd.Context(), args[0], append(args[1:], path)...)
+				editor.Stdin, editor.Stdout, editor.Stderr = os.Stdin, cmd.OutOrStdout(), cmd.ErrOrStderr()
+				if err := editor.Run(); err != nil {
+					return fmt.Errorf("could not run editor: %w", err)
+				}
+				config, err := readConfig(path)
+				if err != nil {
+					return err
+				}
+				return checkConfigTree(cmd.Root(), path, config)
+			},
+		},
+		&cobra.Command{
+			Use:                   "path",
+			Short:                 "Print the path of the config file",
+			DisableFlagsInUseLine: true,
+			Args:                  cobra.NoArgs,
+			RunE: func(cmd *cobra.Command, _ []string) error {
+				path, err := configFileFor(cmd)
+				if err != nil {
+					return err
+				}
+				fmt.Fprintln(cmd.OutOrStdout(), path)
+				return nil
+			},
+		},
+	)
+	return cmd
+}
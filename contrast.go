@@ -0,0 +1,198 @@
+package fang
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+	"slices"
+
+	"charm.land/lipgloss/v2"
+)
+
+// contrastRatio returns the WCAG 2 contrast ratio between two colors, ranging
+// from 1 (no contrast) to 21 (black on white).
+func contrastRatio(a, b color.Color) float64 {
+	la, lb := relativeLuminance(a), relativeLuminance(b)
+	if la < lb {
+		la, lb = lb, la
+	}
+	return (la + 0.05) / (lb + 0.05) //nolint:mnd
+}
+
+// relativeLuminance returns the WCAG 2 relative luminance of a color.
+func relativeLuminance(c color.Color) float64 {
+	r, g, b, _ := c.RGBA()
+	linear := func(v uint32) float64 {
+		s := float64(v) / 0xffff
+		if s <= 0.03928 { //nolint:mnd
+			return s / 12.92 //nolint:mnd
+		}
+		return math.Pow((s+0.055)/1.055, 2.4) //nolint:mnd
+	}
+	return 0.2126*linear(r) + 0.7152*linear(g) + 0.0722*linear(b) //nolint:mnd
+}
+
+// isColor reports whether c is an actual color, as opposed to nil or
+// [lipgloss.NoColor].
+func isColor(c color.Color) bool {
+	if c == nil {
+		return false
+	}
+	_, ok := c.(lipgloss.NoColor)
+	return !ok
+}
+
+// ensureContrast lightens or darkens fg, whichever moves it away from the
+// given backgrounds, until it reaches the given contrast ratio against all of
+// them.
+func ensureContrast(fg color.Color, ratio float64, bgs ...color.Color) color.Color {
+	bgs = slices.DeleteFunc(slices.Clone(bgs), func(bg color.Color) bool { return !isColor(bg) })
+	if !isColor(fg) || len(bgs) == 0 {
+		return fg
+	}
+	const (
+		step     = 0.05
+		maxSteps = 20
+	)
+	// backgrounds darker than this have more contrast with white than
+	// with black.
+	var luminance float64
+	for _, bg := range bgs {
+		luminance += relativeLuminance(bg)
+	}
+	lighten := luminance/float64(len(bgs)) < 0.179 //nolint:mnd
+	for range maxSteps {
+		if !slices.ContainsFunc(bgs, func(bg color.Color) bool { return contrastRatio(fg, bg) < ratio }) {
+			break
+		}
+		if lighten {
+			fg = lipgloss.Lighten(fg, step)
+		} else {
+			fg = lipgloss.Darken(fg, step)
+		}
+	}
+	return fg
+}
+
+// WCAG 2 minimum contrast ratios.
+const (
+	ContrastAA      = 4.5 // normal text, level AA.
+	ContrastAALarge = 3.0 // large or bold text, level AA.
+	ContrastAAA     = 7.0 // normal text, level AAA.
+)
+
+// ContrastIssue is a foreground/background pair rendered by fang whose
+// contrast ratio is below the required minimum.
+type ContrastIssue struct {
+	Style      string // the style rendering the pair, e.g. "Codeblock.Comment"
+	Dark       bool   // whether the pair comes from the dark variant
+	Foreground color.Color
+	Background color.Color
+	Ratio      float64
+}
+
+// String implements [fmt.Stringer].
+func (i ContrastIssue) String() string {
+	variant := "light"
+	if i.Dark {
+		variant = "dark"
+	}
+	return fmt.Sprintf(
+		"%s: %s: contrast ratio %.2f:1 (%s on %s)",
+		variant, i.Style, i.Ratio, colorHex(i.Foreground), colorHex(i.Background),
+	)
+}
+
+// ValidateContrast checks every foreground/background pair fang renders with
+// the given colorscheme, in both its light and dark variants, and returns the
+// pairs with a contrast ratio below minRatio.
+//
+// Text rendered without a background is checked against a white terminal
+// background in the light variant, and a black one in the dark variant.
+//
+// Example:
+//
+//	func TestContrast(t *testing.T) {
+//		for _, issue := range fang.ValidateContrast(myColorScheme, fang.ContrastAA) {
+//			t.Error(issue)
+//		}
+//	}
+func ValidateContrast(cs ColorSchemeFunc, minRatio float64) []ContrastIssue {
+	var issues []ContrastIssue
+	for _, isDark := range []bool{false, true} {
+		c := lipgloss.LightDark(isDark)
+		background := c(lipgloss.Color("#FFFFFF"), lipgloss.Color("#000000"))
+		for _, s := range renderedStyles(makeStyles(cs(c))) {
+			fg, bg := s.style.GetForeground(), s.style.GetBackground()
+			if !isColor(fg) {
+				continue
+			}
+			if !isColor(bg) {
+				bg = background
+			}
+			if ratio := contrastRatio(fg, bg); ratio < minRatio {
+				issues = append(issues, ContrastIssue{
+					Style:      s.name,
+					Dark:       isDark,
+					Foreground: fg,
+					Background: bg,
+					Ratio:      ratio,
+				})
+			}
+		}
+	}
+	return issues
+}
+
+type namedStyle struct {
+	name  string
+	style lipgloss.Style
+}
+
+// renderedStyles returns all the styles fang renders text with.
+func renderedStyles(styles Styles) []namedStyle {
+	result := []namedStyle{
+		{"Text", styles.Text},
+		{"Title", styles.Title},
+		{"ErrorHeader", styles.ErrorHeader},
+		{"ErrorText", styles.ErrorText},
+		{"ErrorDetails", styles.ErrorDetails},
+		{"ErrorHint", styles.ErrorHint},
+		{"FlagDescription", styles.FlagDescription},
+		{"FlagDefault", styles.FlagDefault},
+		{"Link", styles.Link},
+		{"Badge", styles.Badge},
+		{"Codeblock.Base", styles.Codeblock.Base},
+		{"Codeblock.Text", styles.Codeblock.Text},
+		{"Codeblock.Comment", styles.Codeblock.Comment},
+	}
+	for _, program := range []struct {
+		prefix string
+		p      Program
+	}{
+		{"Codeblock.Program", styles.Codeblock.Program},
+		{"Program", styles.Program},
+	} {
+		prefix, p := program.prefix, program.p
+		result = append(
+			result,
+			namedStyle{prefix + ".Name", p.Name},
+			namedStyle{prefix + ".Command", p.Command},
+			namedStyle{prefix + ".Flag", p.Flag},
+			namedStyle{prefix + ".Argument", p.Argument},
+			namedStyle{prefix + ".DimmedArgument", p.DimmedArgument},
+			namedStyle{prefix + ".QuotedString", p.QuotedString},
+			namedStyle{prefix + ".Variable", p.Variable},
+			namedStyle{prefix + ".Operator", p.Operator},
+		)
+	}
+	return result
+}
+
+func colorHex(c color.Color) string {
+	if !isColor(c) {
+		return "none"
+	}
+	r, g, b, _ := c.RGBA()
+	return fmt.Sprintf("#%02X%02X%02X", r>>8, g>>8, b>>8)
+}
@@ -0,0 +1,73 @@
+package fang
+
+import (
+	"image/color"
+	"testing"
+
+	"charm.land/lipgloss/v2"
+	"github.com/stretchr/testify/require"
+)
+
+func TestContrastRatio(t *testing.T) {
+	black, white := lipgloss.Color("#000000"), lipgloss.Color("#FFFFFF")
+	require.InDelta(t, 21, contrastRatio(black, white), 0.01)
+	require.InDelta(t, 21, contrastRatio(white, black), 0.01)
+	require.InDelta(t, 1, contrastRatio(white, white), 0.01)
+}
+
+func TestEnsureContrast(t *testing.T) {
+	for name, bgs := range map[string][]color.Color{
+		"light": {lipgloss.Color("#FFFFFF"), lipgloss.Color("#D8D8D8")},
+		"dark":  {lipgloss.Color("#000000"), lipgloss.Color("#2F2E36")},
+	} {
+		t.Run(name, func(t *testing.T) {
+			fg := ensureContrast(lipgloss.Color("#808080"), ContrastAA, bgs...)
+			for _, bg := range bgs {
+				require.GreaterOrEqual(t, contrastRatio(fg, bg), ContrastAA)
+			}
+		})
+	}
+}
+
+func TestValidateContrast(t *testing.T) {
+	// makeStyles caches the terminal width, keep it consistent with the
+	// golden files.
+	t.Setenv("__FANG_TEST_WIDTH", "45")
+
+	t.Run("unreadable", func(t *testing.T) {
+		issues := ValidateContrast(func(c lipgloss.LightDarkFunc) ColorScheme {
+			cs := DefaultColorScheme(c)
+			cs.Comment = cs.Codeblock
+			cs.ErrorHeader = [2]color.Color{cs.ErrorHeader[1], cs.ErrorHeader[1]}
+			return cs
+		}, ContrastAALarge)
+
+		var light, dark []string
+		for _, issue := range issues {
+			if issue.Dark {
+				dark = append(dark, issue.Style)
+				continue
+			}
+			light = append(light, issue.Style)
+		}
+		for _, styles := range [][]string{light, dark} {
+			require.Contains(t, styles, "Codeblock.Comment")
+			require.Contains(t, styles, "ErrorHeader")
+		}
+		require.Equal(
+			t,
+			"light: ErrorHeader: contrast ratio 1.00:1 (#FF388B on #FF388B)",
+			issues[0].String(),
+		)
+	})
+
+	t.Run("palette", func(t *testing.T) {
+		issues := ValidateContrast(func(c lipgloss.LightDarkFunc) ColorScheme {
+			return NewColorScheme(c, Palette{
+				Primary:   lipgloss.Color("#FFFF00"),
+				Secondary: c(lipgloss.Color("#EEEEEE"), lipgloss.Color("#111111")),
+			})
+		}, ContrastAALarge)
+		require.Empty(t, issues)
+	})
+}
@@ -0,0 +1,163 @@
+package fang
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// PanicError is the error returned by [Execute] when a command panics and
+// [WithCrashReports] is set.
+type PanicError struct {
+	Value  any    // the value passed to panic.
+	Stack  []byte // the stack trace of the goroutine that panicked.
+	Report string // the path of the crash report, if it could be written.
+}
+
+// Error implements error.
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.Value)
+}
+
+// crashEnv lists the environment variables included in crash reports. Only
+// these are included, as the others might hold secrets.
+var crashEnv = []string{
+	"TERM", "TERM_PROGRAM", "COLORTERM", "NO_COLOR", "CLICOLOR_FORCE",
+	"ACCESSIBLE", "LANG", "LC_ALL", "SHELL", "CI",
+}
+
+// invocation records the command being run and its arguments, for crash
+// reports.
+type invocation struct {
+	cmd  *cobra.Command
+	args []string
+}
+
+// record records the given command and arguments, once its flags are parsed.
+func (inv *invocation) record(c *cobra.Command, args []string) error {
+	inv.cmd, inv.args = c, args
+	return nil
+}
+
+// commandLine returns the command line of the recorded command, with the
+// flags that were set and its arguments, and secrets redacted. It falls back
+// to the arguments of the program if the command panicked before its flags
+// were parsed.
+func (inv *invocation) commandLine(root *cobra.Command) string {
+	c, args := inv.cmd, inv.args
+	if c == nil {
+		args = os.Args[1:]
+		c, _, _ = root.Find(args)
+		return strings.Join(append([]string{root.Name()}, redactArgs(c, args)...), " ")
+	}
+	var line []string
+	c.Flags().Visit(func(f *pflag.Flag) {
+		value := f.Value.String()
+		if sv, ok := f.Value.(pflag.SliceValue); ok {
+			value = strings.Join(sv.GetSlice(), ",")
+		}
+		if f.NoOptDefVal != "" && value == f.NoOptDefVal {
+			line = append(line, "--"+f.Name)
+			return
+		}
+		line = append(line, "--"+f.Name+"="+value)
+	})
+	if len(args) > 0 {
+		line = append(append(line, "--"), args...)
+	}
+	return strings.Join(append([]string{c.CommandPath()}, redactArgs(c, line)...), " ")
+}
+
+// crashError writes a crash report for the given panic, and returns the
+// error telling the user about it.
+func crashError(root *cobra.Command, inv *invocation, value any, stack []byte, version string, r redactor) error {
+	perr := &PanicError{Value: value, Stack: stack}
+	rich := &Error{
+		Err:   perr,
+		Title: "Unexpected error",
+		Hint:  "Please attach the crash report when reporting this bug.",
+	}
+
+	path, err := writeCrashReport(root, inv, perr, version, r)
+	if err != nil {
+		rich.Details = fmt.Sprintf("%s crashed, and the crash report could not be written: %v. Its stack trace is printed above.", root.Name(), err)
+		_, _ = root.ErrOrStderr().Write(stack)
+		return rich
+	}
+	perr.Report = path
+	rich.Details = fmt.Sprintf("%s crashed, and wrote a crash report to:\n%s", root.Name(), path)
+	return rich
+}
+
+// writeCrashReport writes the crash report of the given panic in the state
+// directory of the program, and returns its path.
+func writeCrashReport(root *cobra.Command, inv *invocation, perr *PanicError, version string, r redactor) (string, error) {
+	dir, err := stateDir(root)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return "", fmt.Errorf("could not create state directory: %w", err)
+	}
+	now := time.Now()
+	// the random suffix keeps the reports of crashes in the same second.
+	f, err := os.CreateTemp(dir, "crash-"+now.Format("20060102-150405")+"-*.log")
+	if err != nil {
+		return "", fmt.Errorf("could not write crash report: %w", err)
+	}
+	defer f.Close() //nolint:errcheck
+	if _, err := f.WriteString(r.redact(string(crashReport(root, inv, perr, version, now)))); err != nil {
+		return "", fmt.Errorf("could not write crash report: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return "", fmt.Errorf("could not write crash report: %w", err)
+	}
+	return f.Name(), nil
+}
+
+func crashReport(root *cobra.Command, inv *invocation, perr *PanicError, version string, now time.Time) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "%s crash report\n\n", root.Name())
+	fmt.Fprintf(&b, "Time:    %s\n", now.Format(time.RFC3339))
+	fmt.Fprintf(&b, "Version: %s\n", version)
+	fmt.Fprintf(&b, "Go:      %s %s/%s\n", runtime.Version(), runtime.GOOS, runtime.GOARCH)
+	fmt.Fprintf(&b, "Args:    %s\n", inv.commandLine(root))
+	b.WriteString("\nEnvironment:\n")
+	for _, key := range crashEnv {
+		if v, ok := os.LookupEnv(key); ok {
+			fmt.Fprintf(&b, "  %s=%s\n", key, v)
+		}
+	}
+	fmt.Fprintf(&b, "\n%s\n\n", perr.Error())
+	b.Write(perr.Stack)
+	return b.Bytes()
+}
+
+// stateDir returns the directory where the state of the given program, e.g.
+// crash reports, is stored.
+func stateDir(root *cobra.Command) (string, error) {
+	dir := os.Getenv("XDG_STATE_HOME")
+	if dir == "" {
+		if runtime.GOOS == "windows" {
+			var err error
+			dir, err = os.UserCacheDir()
+			if err != nil {
+				return "", fmt.Errorf("could not find state directory: %w", err)
+			}
+		} else {
+			home, err := os.UserHomeDir()
+			if err != nil {
+				return "", fmt.Errorf("could not find state directory: %w", err)
+			}
+			dir = filepath.Join(home, ".local", "state")
+		}
+	}
+	return filepath.Join(dir, root.Name()), nil
+}
@@ -0,0 +1,101 @@
+package fang
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// DestructiveAnnotation is the command annotation marking a command as
+// destructive, e.g. one deleting data. Such commands ask for a confirmation
+// before running, unless the --yes flag fang adds to them is set, and refuse
+// to run without it when the input isn't a terminal. They are marked as
+// destructive in the help.
+//
+// Example:
+//
+//	cmd.Annotations = map[string]string{fang.DestructiveAnnotation: "true"}
+const DestructiveAnnotation = "fang_destructive"
+
+const (
+	yesFlag       = "yes"
+	yesAnnotation = "fang_yes"
+)
+
+var errAborted = errors.New("aborted")
+
+// destructiveBadge returns the badge marking destructive commands in the help.
+func destructiveBadge(styles Styles) string {
+	return styles.Badge.Render("DESTRUCTIVE")
+}
+
+// isDestructive reports whether the given command is marked with
+// [DestructiveAnnotation].
+func isDestructive(c *cobra.Command) bool {
+	v, _ := strconv.ParseBool(c.Annotations[DestructiveAnnotation])
+	return v
+}
+
+// isYesFlag reports whether the given flag is the --yes flag added by
+// [addYesFlags], which can only be set on the command line, so it is not
+// bound to the environment nor set from the config file.
+func isYesFlag(f *pflag.Flag) bool {
+	return len(f.Annotations[yesAnnotation]) > 0
+}
+
+// addYesFlags adds the --yes/-y flag to the destructive commands of the given
+// tree, unless they have or inherit a flag with this name already, and
+// without the shorthand if it is taken. It reports whether there are
+// destructive commands.
+func addYesFlags(root *cobra.Command) bool {
+	var found bool
+	walk(root, func(c *cobra.Command) {
+		if !isDestructive(c) {
+			return
+		}
+		found = true
+		if lookupFlag(c, yesFlag, "") != nil {
+			return
+		}
+		shorthand := "y"
+		if lookupFlag(c, "", shorthand) != nil {
+			shorthand = ""
+		}
+		c.Flags().BoolP(yesFlag, shorthand, false, "Skip the confirmation")
+		_ = c.Flags().SetAnnotation(yesFlag, yesAnnotation, []string{"true"})
+	})
+	return found
+}
+
+// confirmDestructive asks for a confirmation before running the given command
+// if it is destructive and neither --yes nor --dry-run are set, or fails if
+// the session isn't interactive. Only commands supporting dry runs, see
+// [DryRunAnnotation], skip it in dry runs.
+func confirmDestructive(styles func() Styles) func(*cobra.Command, []string) error {
+	return func(c *cobra.Command, _ []string) error {
+		if !isDestructive(c) {
+			return nil
+		}
+		if yes, _ := c.Flags().GetBool(yesFlag); yes || IsDryRun(c.Context()) {
+			return nil
+		}
+		if !isInteractive(c) {
+			return &Error{
+				Err:  fmt.Errorf("refusing to run %s without a confirmation", c.CommandPath()),
+				Hint: "Run it again with --yes to confirm.",
+			}
+		}
+		p := newPrompter(c, styles())
+		yes, err := p.confirm(c.CommandPath()+" is destructive. Are you sure?", false)
+		if err != nil {
+			return fmt.Errorf("could not confirm: %w", err)
+		}
+		if !yes {
+			return errAborted
+		}
+		return nil
+	}
+}
@@ -0,0 +1,164 @@
+package fang
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/charmbracelet/colorprofile"
+	"github.com/spf13/cobra"
+)
+
+// DryRunAnnotation is the command annotation marking a command, and its
+// subcommands, as supporting dry runs, see [WithDryRun]. Such commands check
+// [IsDryRun], and record what they would do with [RecordAction] instead of
+// doing it.
+//
+// Example:
+//
+//	cmd.Annotations = map[string]string{fang.DryRunAnnotation: "true"}
+const DryRunAnnotation = "fang_dry_run"
+
+const dryRunFlag = "dry-run"
+
+type dryRunKey struct{}
+
+// dryRun records the actions the command would have taken, see
+// [RecordAction].
+type dryRun struct {
+	mu      sync.Mutex
+	enabled bool
+	actions []string
+}
+
+func dryRunFrom(ctx context.Context) *dryRun {
+	d, _ := ctx.Value(dryRunKey{}).(*dryRun)
+	return d
+}
+
+// IsDryRun reports whether the command was run with --dry-run, see
+// [WithDryRun] and [DryRunAnnotation], in which case it should only record what it would do with
+// [RecordAction], and not do it.
+//
+// Example:
+//
+//	if fang.IsDryRun(cmd.Context()) {
+//		fang.RecordAction(cmd.Context(), "Delete database %s", name)
+//		return nil
+//	}
+func IsDryRun(ctx context.Context) bool {
+	d := dryRunFrom(ctx)
+	if d == nil {
+		return false
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.enabled
+}
+
+// RecordAction records an action the command would take, formatted as with
+// [fmt.Sprintf], to be listed in the summary of the dry run once the command
+// is done. It does nothing if the command wasn't run with --dry-run, and it
+// is safe to call from several goroutines.
+func RecordAction(ctx context.Context, format string, args ...any) {
+	d := dryRunFrom(ctx)
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.enabled {
+		d.actions = append(d.actions, fmt.Sprintf(format, args...))
+	}
+}
+
+// supportsDryRun reports whether the given command, or one of its parents, is
+// marked with [DryRunAnnotation].
+func supportsDryRun(c *cobra.Command) bool {
+	for p := c; p != nil; p = p.Parent() {
+		if v, _ := strconv.ParseBool(p.Annotations[DryRunAnnotation]); v {
+			return true
+		}
+	}
+	return false
+}
+
+// addDryRunFlags adds the persistent --dry-run flag to the commands of the
+// given tree marked with [DryRunAnnotation], unless they have or inherit a
+// flag with this name already. It reports whether there are such commands.
+func addDryRunFlags(root *cobra.Command) bool {
+	var found bool
+	walk(root, func(c *cobra.Command) {
+		if !supportsDryRun(c) {
+			return
+		}
+		found = true
+		if lookupFlag(c, dryRunFlag, "") != nil {
+			return
+		}
+		c.PersistentFlags().Bool(dryRunFlag, false, "Print what would be done, without doing it")
+	})
+	return found
+}
+
+// enable starts a dry run if the given command supports them, and --dry-run
+// is set, forgetting the actions of any previous run.
+func (d *dryRun) enable(c *cobra.Command, _ []string) error {
+	var enabled bool
+	if supportsDryRun(c) {
+		enabled, _ = c.Flags().GetBool(dryRunFlag)
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.enabled = enabled
+	d.actions = nil
+	return nil
+}
+
+// writeSummary writes the actions recorded during the dry run, if any.
+func (d *dryRun) writeSummary(w *colorprofile.Writer, styles Styles) {
+	if styles.accessible {
+		d.writePlainSummary(w)
+		return
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if !d.enabled {
+		return
+	}
+
+	_, _ = fmt.Fprintln(w, styles.Title.Render("dry run"))
+	for _, action := range d.actions {
+		_, _ = fmt.Fprintln(w, strings.Repeat(" ", longPad)+
+			styles.FlagDefault.Render("•")+" "+styles.Text.Render(action))
+	}
+	if len(d.actions) > 0 {
+		_, _ = fmt.Fprintln(w)
+	}
+	_, _ = fmt.Fprintln(w, strings.Repeat(" ", shortPad)+
+		styles.FlagDefault.Render("Nothing was done, as ")+
+		styles.Program.Flag.Render("--dry-run")+
+		styles.FlagDefault.Render(" is set."))
+	_, _ = fmt.Fprintln(w)
+}
+
+// writePlainSummary writes the actions recorded during the dry run, if any,
+// as plain text, as used by accessible mode and MCP tools.
+func (d *dryRun) writePlainSummary(w io.Writer) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if !d.enabled {
+		return
+	}
+
+	_, _ = fmt.Fprintln(w)
+	_, _ = fmt.Fprintln(w, "Dry run:")
+	for _, action := range d.actions {
+		_, _ = fmt.Fprintln(w, "Action: "+punctuate(action))
+	}
+	_, _ = fmt.Fprintln(w, "Nothing was done, as --dry-run is set.")
+}
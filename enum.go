@@ -0,0 +1,40 @@
+package fang
+
+import (
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+const enumAnnotation = "fang_enum"
+
+// MarkFlagEnum declares the values accepted by the flag with the given name
+// in the given flag set, so they can be offered in generated specs and
+// prompts.
+//
+// Example:
+//
+//	cmd.Flags().String("output", "text", "Output format")
+//	_ = fang.MarkFlagEnum(cmd.Flags(), "output", "text", "json")
+func MarkFlagEnum(flags *pflag.FlagSet, name string, values ...string) error {
+	//nolint:wrapcheck
+	return flags.SetAnnotation(name, enumAnnotation, values)
+}
+
+// flagEnum returns the values accepted by the given flag, if declared with
+// [MarkFlagEnum].
+func flagEnum(f *pflag.Flag) []string {
+	return f.Annotations[enumAnnotation]
+}
+
+// argEnum returns the values accepted by the positional arguments of the
+// given command, from its ValidArgs, without their descriptions.
+func argEnum(c *cobra.Command) []string {
+	values := make([]string, 0, len(c.ValidArgs))
+	for _, arg := range c.ValidArgs {
+		value, _, _ := strings.Cut(arg, "\t")
+		values = append(values, value)
+	}
+	return values
+}
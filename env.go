@@ -0,0 +1,90 @@
+package fang
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+const envAnnotation = "fang_env"
+
+// BindFlagEnv binds the flag with the given name in the given flag set to the
+// given environment variable, which sets the flag when it is not set on the
+// command line. It takes precedence over the name derived from
+// [WithEnvPrefix].
+//
+// Example:
+//
+//	cmd.Flags().String("token", "", "API token")
+//	_ = fang.BindFlagEnv(cmd.Flags(), "token", "GITHUB_TOKEN")
+func BindFlagEnv(flags *pflag.FlagSet, name, env string) error {
+	//nolint:wrapcheck
+	return flags.SetAnnotation(name, envAnnotation, []string{env})
+}
+
+// flagEnv returns the environment variable the given flag is bound to, if
+// any.
+func flagEnv(f *pflag.Flag) string {
+	if env := f.Annotations[envAnnotation]; len(env) > 0 {
+		return env[0]
+	}
+	return ""
+}
+
+// envName returns the name of the environment variable of the flag with the
+// given name, e.g. APP_DRY_RUN for --dry-run with the APP prefix.
+func envName(prefix, name string) string {
+	name = strings.NewReplacer("-", "_", ".", "_").Replace(name)
+	return strings.ToUpper(prefix + "_" + name)
+}
+
+// bindEnv binds the flags of the given command tree not bound yet to the
+// environment variables named after them with the given prefix.
+func bindEnv(root *cobra.Command, prefix string) {
+	walk(root, func(c *cobra.Command) {
+		visitOwnFlags(c, func(f *pflag.Flag) {
+			if isDefaultFlag(f.Name) || isYesFlag(f) || flagEnv(f) != "" {
+				return
+			}
+			if f.Annotations == nil {
+				f.Annotations = map[string][]string{}
+			}
+			f.Annotations[envAnnotation] = []string{envName(prefix, f.Name)}
+		})
+	})
+}
+
+// applyEnv sets the flags of the given command not set on the command line
+// from the environment variables they are bound to.
+func applyEnv(c *cobra.Command, _ []string) error {
+	var err error
+	c.Flags().VisitAll(func(f *pflag.Flag) {
+		env := flagEnv(f)
+		if err != nil || env == "" || f.Changed {
+			return
+		}
+		value, ok := os.LookupEnv(env)
+		if !ok || value == "" {
+			return
+		}
+		if serr := c.Flags().Set(f.Name, value); serr != nil {
+			err = fmt.Errorf("invalid value for --%s from $%s: %w", f.Name, env, serr)
+		}
+	})
+	return err
+}
+
+// hasEnv reports whether any flag of the given command tree is bound to an
+// environment variable.
+func hasEnv(root *cobra.Command) bool {
+	found := false
+	walk(root, func(c *cobra.Command) {
+		visitOwnFlags(c, func(f *pflag.Flag) {
+			found = found || flagEnv(f) != ""
+		})
+	})
+	return found
+}
@@ -0,0 +1,252 @@
+package fang
+
+import (
+	"errors"
+	"strings"
+
+	"charm.land/lipgloss/v2"
+)
+
+// Error is an error with guidance for the user, each part rendered in its own
+// styled area by [DefaultErrorHandler].
+//
+// Example:
+//
+//	return &fang.Error{
+//		Err:     err,
+//		Title:   "Not logged in",
+//		Details: "Deploying requires a session, which expired 2 days ago.",
+//		Hint:    "Log in again and retry:",
+//		Command: "app login --sso",
+//		URL:     "https://example.com/docs/login",
+//	}
+type Error struct {
+	Err     error  // the underlying error, rendered as the message.
+	Title   string // replaces the "ERROR" header.
+	Details string // a longer explanation of what happened.
+	Hint    string // what the user can do about it.
+	Command string // a command to run to fix it.
+	URL     string // a link to the documentation, see [DocsLinker].
+}
+
+// Error implements error.
+func (e *Error) Error() string {
+	if e.Err == nil {
+		return e.Title
+	}
+	return e.Err.Error()
+}
+
+// Unwrap returns the underlying error.
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// DocsURL implements [DocsLinker].
+func (e *Error) DocsURL() string {
+	return e.URL
+}
+
+// plainGuidance returns the details, hint, command and docs link of the
+// given error as plain, labeled lines.
+func plainGuidance(err error) []string {
+	var lines []string
+	var rich *Error
+	if errors.As(err, &rich) {
+		if rich.Details != "" {
+			lines = append(lines, "Details: "+punctuate(rich.Details))
+		}
+		if rich.Hint != "" {
+			lines = append(lines, "Hint: "+punctuate(rich.Hint))
+		}
+		if rich.Command != "" {
+			lines = append(lines, "Run: "+rich.Command)
+		}
+	}
+	if url := docsURL(err); url != "" {
+		lines = append(lines, "Learn more: "+url)
+	}
+	return lines
+}
+
+// styleCommand highlights a single command line, e.g. the command of an
+// [Error].
+func styleCommand(command string, styles Program) string {
+	var (
+		lexer shellLexer
+		sb    strings.Builder
+	)
+	first := true
+	for _, token := range lexer.lex(command) {
+		style := lipgloss.NewStyle()
+		switch token.kind {
+		case shellOperator, shellRedirect:
+			style = styles.Operator
+			first = first || token.kind == shellOperator
+		case shellWord:
+			switch {
+			case first:
+				style = styles.Name
+				first = false
+			case strings.HasPrefix(token.text, "-"):
+				style = styles.Flag
+			case token.quoted():
+				style = styles.QuotedString
+			default:
+				style = styles.Argument
+			}
+		}
+		sb.WriteString(style.Render(token.text))
+	}
+	return sb.String()
+}
+
+// errorLine is a line of an error trace.
+type errorLine struct {
+	depth int
+	kind  errorLineKind
+	text  string
+}
+
+type errorLineKind int
+
+const (
+	errorMessage errorLineKind = iota // the outermost message.
+	errorCause                        // an error wrapped by the line above.
+	errorItem                         // one of several joined errors.
+)
+
+// errorTrace breaks the given error down into its wrapped causes, outermost
+// first, and its joined errors, e.g. from [errors.Join], as a list.
+func errorTrace(err error) []errorLine {
+	return appendErrorTrace(nil, err, 0, errorMessage)
+}
+
+func appendErrorTrace(lines []errorLine, err error, depth int, kind errorLineKind) []errorLine {
+	messages, joined := unwrapMessages(err)
+	for i, msg := range messages {
+		if i > 0 {
+			kind = errorCause
+		}
+		lines = append(lines, errorLine{depth: depth + i, kind: kind, text: msg})
+	}
+	if len(messages) > 0 {
+		depth += len(messages)
+	}
+	for _, err := range joined {
+		lines = appendErrorTrace(lines, err, depth, errorItem)
+	}
+	return lines
+}
+
+// unwrapMessages returns the messages of each error of the chain, without
+// the messages of the errors they wrap, and the joined errors ending it, if
+// any.
+//
+// Wrapping errors which don't follow the "message: cause" convention end the
+// chain, as their causes can't be told apart from their own message.
+func unwrapMessages(err error) ([]string, []error) {
+	var messages []string
+	for err != nil {
+		msg := err.Error()
+		if u, ok := err.(interface{ Unwrap() []error }); ok {
+			var errs []error
+			var msgs []string
+			for _, err := range u.Unwrap() {
+				if err != nil {
+					errs = append(errs, err)
+					msgs = append(msgs, err.Error())
+				}
+			}
+			switch {
+			case len(errs) == 1 && msg == msgs[0]:
+				err = errs[0]
+				continue
+			case len(errs) > 1 && msg == strings.Join(msgs, "\n"):
+				return messages, errs
+			}
+			return append(messages, msg), nil
+		}
+
+		inner := errors.Unwrap(err)
+		if inner == nil {
+			return append(messages, msg), nil
+		}
+		cause := inner.Error()
+		switch {
+		case msg == cause:
+			// e.g. an [Error], which has no message of its own.
+		case strings.HasSuffix(msg, ": "+cause):
+			messages = append(messages, strings.TrimSuffix(msg, ": "+cause))
+		default:
+			return append(messages, msg), nil
+		}
+		err = inner
+	}
+	return messages, nil
+}
+
+// renderErrorTrace renders the given error trace with the error text style,
+// indenting causes and listing joined errors.
+func renderErrorTrace(styles Styles, lines []errorLine) string {
+	text := styles.ErrorText
+	margin := text.GetMarginLeft()
+	text = text.UnsetMarginLeft()
+	marker := styles.ErrorDetails.UnsetMargins().UnsetWidth()
+
+	rendered := make([]string, 0, len(lines))
+	for i, line := range lines {
+		indent := 2 * line.depth
+		var prefix string
+		switch line.kind {
+		case errorCause:
+			prefix = "↳ "
+		case errorItem:
+			prefix = "• "
+		}
+		// only the top-level message is capitalized and punctuated, causes
+		// are kept verbatim, as they often start or end with identifiers.
+		style, msg := text.UnsetTransform(), line.text
+		if isTopLevel(i, line) {
+			style, msg = text, punctuate(msg)
+		}
+		if w := text.GetWidth(); w > 0 {
+			style = style.Width(max(w-indent-lipgloss.Width(prefix), minErrorWidth))
+		}
+		s := style.Render(msg)
+		if prefix != "" {
+			s = lipgloss.JoinHorizontal(lipgloss.Top, marker.Render(prefix), s)
+		}
+		rendered = append(rendered, lipgloss.NewStyle().MarginLeft(margin+indent).Render(s))
+	}
+	return strings.Join(rendered, "\n")
+}
+
+// isTopLevel reports whether the given line of an error trace, at the given
+// index, is the top-level message of the error.
+func isTopLevel(i int, line errorLine) bool {
+	return i == 0 && line.depth == 0 && line.kind == errorMessage
+}
+
+// minErrorWidth is the narrowest deeply nested errors get wrapped at.
+const minErrorWidth = 20
+
+// plainErrorTrace returns the given error trace as plain lines, for
+// [WithAccessible].
+func plainErrorTrace(lines []errorLine) []string {
+	plain := make([]string, 0, len(lines))
+	for i, line := range lines {
+		text := line.text
+		if isTopLevel(i, line) {
+			text = titleFirstWord(punctuate(text))
+		}
+		switch line.kind {
+		case errorCause:
+			text = "Caused by: " + text
+		case errorItem:
+			text = "- " + text
+		}
+		plain = append(plain, strings.Repeat(" ", 2*line.depth)+text)
+	}
+	return plain
+}
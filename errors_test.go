@@ -0,0 +1,78 @@
+package fang
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type opaqueError struct{ err error }
+
+func (e opaqueError) Error() string { return "opaque (" + e.err.Error() + ")" }
+func (e opaqueError) Unwrap() error { return e.err }
+
+func TestErrorTrace(t *testing.T) {
+	base := errors.New("connection refused")
+	for name, tt := range map[string]struct {
+		err  error
+		want []errorLine
+	}{
+		"plain": {
+			err:  base,
+			want: []errorLine{{0, errorMessage, "connection refused"}},
+		},
+		"wrapped": {
+			err: fmt.Errorf("could not deploy: %w", fmt.Errorf("dial db: %w", base)),
+			want: []errorLine{
+				{0, errorMessage, "could not deploy"},
+				{1, errorCause, "dial db"},
+				{2, errorCause, "connection refused"},
+			},
+		},
+		"not wrapped": {
+			err:  fmt.Errorf("could not deploy: %v", base),
+			want: []errorLine{{0, errorMessage, "could not deploy: connection refused"}},
+		},
+		"rich": {
+			err: fmt.Errorf("could not deploy: %w", &Error{Err: base, Title: "Offline"}),
+			want: []errorLine{
+				{0, errorMessage, "could not deploy"},
+				{1, errorCause, "connection refused"},
+			},
+		},
+		"opaque": {
+			err:  fmt.Errorf("could not deploy: %w", opaqueError{base}),
+			want: []errorLine{{0, errorMessage, "could not deploy"}, {1, errorCause, "opaque (connection refused)"}},
+		},
+		"joined": {
+			err: errors.Join(errors.New("a failed"), fmt.Errorf("b failed: %w", base)),
+			want: []errorLine{
+				{0, errorItem, "a failed"},
+				{0, errorItem, "b failed"},
+				{1, errorCause, "connection refused"},
+			},
+		},
+		"wrapped join": {
+			err: fmt.Errorf("2 of 3 jobs failed: %w", errors.Join(errors.New("a failed"), nil, errors.New("b failed"))),
+			want: []errorLine{
+				{0, errorMessage, "2 of 3 jobs failed"},
+				{1, errorItem, "a failed"},
+				{1, errorItem, "b failed"},
+			},
+		},
+		"single join": {
+			err:  errors.Join(base),
+			want: []errorLine{{0, errorMessage, "connection refused"}},
+		},
+		"multiple %w": {
+			err:  fmt.Errorf("%w and %w", errors.New("a"), errors.New("b")),
+			want: []errorLine{{0, errorMessage, "a and b"}},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			require.Equal(t, tt.want, errorTrace(tt.err))
+		})
+	}
+}
@@ -0,0 +1,135 @@
+package fang
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// exampleRole is the role of a word in an example command line.
+type exampleRole int
+
+const (
+	roleNone           exampleRole = iota // not a word.
+	roleAssignment                        // NAME=value before a command.
+	roleProgram                           // the name of our program.
+	roleCommand                           // the name of another program.
+	roleSubcommand                        // a subcommand of our program.
+	roleFlag                              // a flag, with or without its value.
+	roleArgument                          // any other argument.
+	roleRedirectTarget                    // the target of a redirection.
+)
+
+// exampleToken is a shell token along with its role in the example.
+type exampleToken struct {
+	shellToken
+	role exampleRole
+}
+
+// exampleParser parses the lines of a command's examples, figuring out the
+// role of each word in it.
+type exampleParser struct {
+	root  *cobra.Command
+	lexer shellLexer
+
+	commandStart bool     // the next word starts a command.
+	program      bool     // the current command runs our program.
+	args         []string // non-flag arguments, to resolve subcommands.
+	redirect     bool     // the next word is the target of a redirection.
+
+	words       []exampleToken   // words of the current command, if it runs our program.
+	invocations [][]exampleToken // finished commands running our program.
+}
+
+// exampleLines returns the lines of the given examples, trimmed, and without
+// the leading and trailing empty lines.
+func exampleLines(example string) []string {
+	if example == "" {
+		return nil
+	}
+	var result []string
+	lines := strings.Split(example, "\n")
+	for i, line := range lines {
+		line = strings.TrimSpace(line)
+		if (i == 0 || i == len(lines)-1) && line == "" {
+			continue
+		}
+		result = append(result, line)
+	}
+	return result
+}
+
+func newExampleParser(c *cobra.Command) *exampleParser {
+	return &exampleParser{
+		root:         c.Root(),
+		commandStart: true,
+	}
+}
+
+// parse parses the next line of the examples.
+func (p *exampleParser) parse(line string) []exampleToken {
+	shellTokens := p.lexer.lex(line)
+	tokens := make([]exampleToken, 0, len(shellTokens))
+	for _, token := range shellTokens {
+		role := roleNone
+		switch token.kind {
+		case shellOperator:
+			p.endCommand()
+		case shellRedirect:
+			p.redirect = true
+		case shellWord:
+			role = p.word(token)
+			if p.program && role != roleRedirectTarget {
+				p.words = append(p.words, exampleToken{token, role})
+			}
+		}
+		tokens = append(tokens, exampleToken{token, role})
+	}
+	if !continues(tokens) && p.lexer.quote == 0 {
+		p.endCommand()
+	}
+	return tokens
+}
+
+func (p *exampleParser) endCommand() {
+	if p.program {
+		p.invocations = append(p.invocations, p.words)
+	}
+	p.words = nil
+	p.commandStart = true
+	p.program = false
+	p.args = nil
+	p.redirect = false
+}
+
+func (p *exampleParser) word(token shellToken) exampleRole {
+	if p.redirect {
+		p.redirect = false
+		return roleRedirectTarget
+	}
+
+	value := token.value()
+	if p.commandStart {
+		if assignment(token) > 0 {
+			return roleAssignment
+		}
+		p.commandStart = false
+		p.program = value == p.root.Name() || slices.Contains(p.root.Aliases, value)
+		if p.program {
+			return roleProgram
+		}
+		return roleCommand
+	}
+
+	if strings.HasPrefix(value, "-") && value != "-" && !strings.HasPrefix(token.text, `"`) && !strings.HasPrefix(token.text, "'") {
+		return roleFlag
+	}
+	if p.program && !token.quoted() {
+		p.args = append(p.args, value)
+		if isSubCommand(p.root, p.args, value) {
+			return roleSubcommand
+		}
+	}
+	return roleArgument
+}
@@ -0,0 +1,16 @@
+package fang
+
+import (
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+// SetInteractive makes the prompts behave as if the user could, or couldn't,
+// answer them, for the duration of the test.
+func SetInteractive(tb testing.TB, interactive bool) {
+	tb.Helper()
+	prev := isInteractive
+	isInteractive = func(*cobra.Command) bool { return interactive }
+	tb.Cleanup(func() { isInteractive = prev })
+}
@@ -2,12 +2,17 @@
 package fang
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"os/signal"
+	"regexp"
 	"runtime/debug"
+	"strconv"
+	"strings"
 
 	"charm.land/lipgloss/v2"
 	"github.com/charmbracelet/colorprofile"
@@ -24,6 +29,10 @@ type ErrorHandler = func(w io.Writer, styles Styles, err error)
 // ColorSchemeFunc gets a [lipgloss.LightDarkFunc] and returns a [ColorScheme].
 type ColorSchemeFunc = func(lipgloss.LightDarkFunc) ColorScheme
 
+// StylesFunc gets the [Styles] derived from the [ColorScheme] and returns the
+// [Styles] to use.
+type StylesFunc = func(Styles) Styles
+
 type settings struct {
 	completions bool
 	manpages    bool
@@ -31,8 +40,25 @@ type settings struct {
 	version     string
 	commit      string
 	colorscheme ColorSchemeFunc
+	stylesFunc  StylesFunc
 	errHandler  ErrorHandler
 	signals     []os.Signal
+	accessible  bool
+	hyperlinks  bool
+
+	themeCommand      bool
+	namedColorSchemes []namedColorScheme
+
+	mcp               bool
+	screenshotCommand bool
+	crashReports      bool
+	jsonErrors        bool
+	redactPatterns    []*regexp.Regexp
+	envPrefix         string
+	config            bool
+	configCommand     bool
+	prompts           bool
+	dryRun            bool
 }
 
 // Option changes fang settings.
@@ -59,6 +85,205 @@ func WithColorSchemeFunc(cs ColorSchemeFunc) Option {
 	}
 }
 
+// WithStylesFunc sets a function that customizes the styles derived from the
+// colorscheme, e.g. to change title casing, margins, paddings, transforms or
+// the error header text.
+//
+// Example:
+//
+//	fang.WithStylesFunc(func(s fang.Styles) fang.Styles {
+//		s.Title = s.Title.UnsetTransform()
+//		s.FlagDescription = s.FlagDescription.UnsetTransform()
+//		s.ErrorHeader = s.ErrorHeader.SetString("OOPS")
+//		return s
+//	})
+func WithStylesFunc(fn StylesFunc) Option {
+	return func(s *settings) {
+		s.stylesFunc = fn
+	}
+}
+
+// WithAccessible renders help and errors as plain, linear text that works
+// well with screen readers: no background colors, no alignment padding, no
+// uppercase transforms, and explicit labels.
+//
+// Accessible mode is also enabled when the ACCESSIBLE environment variable is
+// set to a true value, e.g. ACCESSIBLE=1.
+func WithAccessible() Option {
+	return func(s *settings) {
+		s.accessible = true
+	}
+}
+
+// WithNamedColorScheme registers a colorscheme under the given name, so users
+// can pick it with the `theme` command.
+//
+// The built-in "default" and "ansi" colorschemes are always registered, and
+// can be replaced by registering a colorscheme with the same name.
+func WithNamedColorScheme(name string, cs ColorSchemeFunc) Option {
+	return func(s *settings) {
+		s.namedColorSchemes = append(s.namedColorSchemes, namedColorScheme{name, cs})
+	}
+}
+
+// WithThemeCommand adds a hidden `theme` command that lists and previews the
+// available colorschemes, and lets the user persist their choice.
+//
+// The chosen colorscheme takes precedence over the one set with
+// [WithColorSchemeFunc]. It is saved under the theme key of the config file
+// if [WithConfig] is set, and in a theme file in the config directory
+// otherwise.
+func WithThemeCommand() Option {
+	return func(s *settings) {
+		s.themeCommand = true
+	}
+}
+
+// WithMCP adds a hidden `mcp` command that serves the runnable leaf commands
+// as tools over a local MCP (Model Context Protocol) stdio server, so AI
+// agents can run them.
+//
+// Each tool takes the flags of its command by name, and its positional
+// arguments in "args". Use [MCPAnnotation] to control which commands are
+// exposed. Destructive commands aren't, unless allowed explicitly.
+func WithMCP() Option {
+	return func(s *settings) {
+		s.mcp = true
+	}
+}
+
+// WithScreenshotCommand adds a hidden `screenshot` command that renders the
+// help of a command, or an error, to SVG or HTML, see [RenderHelp].
+//
+// Example:
+//
+//	myapp screenshot sub --format html --width 100 --dark > docs/sub.html
+func WithScreenshotCommand() Option {
+	return func(s *settings) {
+		s.screenshotCommand = true
+	}
+}
+
+// WithCrashReports recovers from panics in commands: instead of the raw stack
+// trace, an "unexpected error" is shown, and a crash report with the stack
+// trace, the arguments, the version and a summary of the environment is
+// written to the state directory of the program, for users to attach to bug
+// reports.
+//
+// [Execute] then returns an error wrapping a [PanicError].
+func WithCrashReports() Option {
+	return func(s *settings) {
+		s.crashReports = true
+	}
+}
+
+// WithJSONErrors writes errors to stderr as single line JSON objects, with
+// their message, [ErrorKind], [ExitCode], command path, hint and causes,
+// instead of going through the [ErrorHandler], for programs wrapping yours.
+//
+// Users can also enable it by setting FANG_ERROR_FORMAT=json.
+func WithJSONErrors() Option {
+	return func(s *settings) {
+		s.jsonErrors = true
+	}
+}
+
+// WithRedactPatterns registers patterns matching secrets to redact from
+// errors and crash reports, on top of the values of the flags marked with
+// [MarkFlagSensitive]. Only the submatches of patterns having some are
+// redacted.
+//
+// Example:
+//
+//	fang.WithRedactPatterns(
+//		regexp.MustCompile(`ghp_[A-Za-z0-9]{36}`),
+//		regexp.MustCompile(`(?i)authorization: bearer (\S+)`),
+//	)
+func WithRedactPatterns(patterns ...*regexp.Regexp) Option {
+	return func(s *settings) {
+		s.redactPatterns = append(s.redactPatterns, patterns...)
+	}
+}
+
+// WithEnvPrefix binds every flag to an environment variable named after it
+// with the given prefix, e.g. APP_DRY_RUN for --dry-run with the APP prefix,
+// which sets the flag when it is not set on the command line. Use
+// [BindFlagEnv] to bind a flag to another variable.
+//
+// The variables are shown next to their flags in the help, and in its
+// environment section.
+func WithEnvPrefix(prefix string) Option {
+	return func(s *settings) {
+		s.envPrefix = prefix
+	}
+}
+
+// WithConfig loads a config file setting the flags of the commands, from the
+// path set with the --config flag, or from config.toml, config.yaml or
+// config.json in the config directory of the program, e.g.
+// ~/.config/myapp/config.toml.
+//
+// Keys are flag names, and tables named after subcommands hold their flags.
+// Flags set on the command line take precedence over the environment, see
+// [WithEnvPrefix], which takes precedence over the config file.
+//
+// Example:
+//
+//	region = "eu"
+//
+//	[deploy]
+//	replicas = 3
+//
+// The --show-config flag prints the flags of the command, and where their
+// values come from.
+func WithConfig() Option {
+	return func(s *settings) {
+		s.config = true
+	}
+}
+
+// WithConfigCommand adds a `config` command to list, get, set, unset and edit
+// the settings in the config file, see [WithConfig], which it enables.
+//
+// Keys are the names of the flags, prefixed with the path of their command,
+// e.g. "deploy.replicas" for the --replicas flag of the deploy command.
+// Keys which aren't flags are rejected.
+//
+// Setting or unsetting a key encodes the whole file again, sorting its keys.
+// As that would drop their comments, files with comments are left alone:
+// edit them with `config edit` instead.
+//
+// The config command is never exposed over MCP, see [WithMCP].
+func WithConfigCommand() Option {
+	return func(s *settings) {
+		s.config = true
+		s.configCommand = true
+	}
+}
+
+// WithPrompts asks for the missing required flags and positional arguments,
+// as named in the Use line of the command, instead of failing, when the
+// program runs in an interactive terminal: enums are picked from a list,
+// booleans are confirmed, and other values are typed in. Sessions which
+// aren't interactive fail as usual.
+func WithPrompts() Option {
+	return func(s *settings) {
+		s.prompts = true
+	}
+}
+
+// WithDryRun adds a persistent --dry-run flag to the commands marked with
+// [DryRunAnnotation]. They check it with [IsDryRun], and record what they
+// would do with [RecordAction] instead of doing it, which is summarized on
+// the error output once they are done. Destructive commands, see
+// [DestructiveAnnotation], which support dry runs don't ask for a
+// confirmation in them.
+func WithDryRun() Option {
+	return func(s *settings) {
+		s.dryRun = true
+	}
+}
+
 // WithTheme sets the colorscheme.
 //
 // Deprecated: use [WithColorSchemeFunc] instead.
@@ -119,6 +344,14 @@ func Execute(ctx context.Context, root *cobra.Command, options ...Option) error
 		option(&opts)
 	}
 
+	if v, _ := strconv.ParseBool(os.Getenv("ACCESSIBLE")); v {
+		opts.accessible = true
+	}
+	opts.hyperlinks = supportsHyperlinks(os.Environ())
+	if strings.EqualFold(os.Getenv(errorFormatEnv), "json") {
+		opts.jsonErrors = true
+	}
+
 	// Enable VT processing on Windows, otherwise, ANSI escape sequences might not work on older
 	// Windows versions. This is a no-op on other platforms.
 	for _, w := range []io.Writer{root.OutOrStdout(), root.ErrOrStderr()} {
@@ -127,9 +360,21 @@ func Execute(ctx context.Context, root *cobra.Command, options ...Option) error
 		_ = enableVirtualTerminalProcessing(w)
 	}
 
-	helpFunc := func(c *cobra.Command, _ []string) {
-		w := colorprofile.NewWriter(c.OutOrStdout(), os.Environ())
-		helpFn(c, w, makeStyles(mustColorscheme(opts.colorscheme)))
+	helpFunc := func(c *cobra.Command, args []string) {
+		// render to a buffer first, so nothing is written if rendering
+		// fails midway, and fall back to cobra's default help then.
+		var buf bytes.Buffer
+		w := &colorprofile.Writer{
+			Forward: &buf,
+			Profile: colorprofile.Detect(c.OutOrStdout(), os.Environ()),
+		}
+		if err := safeHelpFn(c, w, func() Styles {
+			return opts.styles(mustColorscheme(opts.colorSchemeFor(c)))
+		}); err != nil {
+			plainHelpFn(c, args)
+			return
+		}
+		_, _ = io.Copy(c.OutOrStdout(), &buf)
 	}
 
 	root.SilenceUsage = true
@@ -160,24 +405,148 @@ func Execute(ctx context.Context, root *cobra.Command, options ...Option) error
 		})
 	}
 
+	if opts.themeCommand {
+		root.AddCommand(themeCmd(opts))
+	}
+
+	if opts.mcp {
+		root.AddCommand(mcpCmd(opts))
+	}
+
+	if opts.screenshotCommand {
+		root.AddCommand(screenshotCmd(opts))
+	}
+
 	if !opts.completions {
 		root.CompletionOptions.DisableDefaultCmd = true
 	}
 
+	if opts.config {
+		addConfigFlags(root)
+	}
+	if opts.configCommand && findSubCommand(root, "config") == nil {
+		root.AddCommand(configCmd(opts))
+	}
+	destructive := addYesFlags(root)
+	recorder := &dryRun{}
+	dryRuns := opts.dryRun && addDryRunFlags(root)
+	if dryRuns {
+		ctx = context.WithValue(ctx, dryRunKey{}, recorder)
+	}
+	if opts.envPrefix != "" {
+		bindEnv(root, opts.envPrefix)
+	}
+	// the theme is read again once the flags are parsed, as --config may
+	// point to another config file.
+	colorscheme := opts.colorSchemeFor(root)
+	stylesFn := func() Styles {
+		return opts.styles(mustColorscheme(colorscheme))
+	}
+	sources := flagSources{}
+	inv := &invocation{}
+	var before []func(*cobra.Command, []string) error
+	if opts.themeCommand {
+		before = append(before, func(c *cobra.Command, _ []string) error {
+			colorscheme = opts.colorSchemeFor(c)
+			return nil
+		})
+	}
+	if opts.crashReports {
+		before = append(before, inv.record)
+	}
+	if hasEnv(root) {
+		before = append(before, sources.applyEnv)
+	}
+	if opts.config {
+		before = append(before, sources.applyConfig, sources.showConfig(stylesFn))
+	}
+	if dryRuns {
+		before = append(before, recorder.enable)
+	}
+	if opts.prompts {
+		before = append(before, promptFlags(stylesFn))
+	}
+	if destructive {
+		before = append(before, confirmDestructive(stylesFn))
+	}
+	if len(before) > 0 {
+		beforeRun(root, func(c *cobra.Command, args []string) error {
+			for _, fn := range before {
+				if err := fn(c, args); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	}
+	if opts.prompts {
+		promptArgs(root, stylesFn)
+	}
+
 	if len(opts.signals) > 0 {
 		var cancel context.CancelFunc
 		ctx, cancel = signal.NotifyContext(ctx, opts.signals...)
 		defer cancel()
 	}
 
-	if err := root.ExecuteContext(ctx); err != nil {
+	cmd, err := opts.execute(ctx, root, inv)
+	if err != nil {
+		if errors.Is(err, errSkipRun) {
+			return nil
+		}
+		redactor := newRedactor(root, opts.redactPatterns)
+		if opts.jsonErrors {
+			writeJSONError(root.ErrOrStderr(), cmd, err, redactor.redact)
+			return err //nolint:wrapcheck
+		}
+		if cmd != nil {
+			colorscheme = opts.colorSchemeFor(cmd)
+		}
 		w := colorprofile.NewWriter(root.ErrOrStderr(), os.Environ())
-		opts.errHandler(w, makeStyles(mustColorscheme(opts.colorscheme)), err)
+		styles := stylesFn()
+		styles.redact = redactor.redact
+		opts.errHandler(w, styles, err)
 		return err //nolint:wrapcheck
 	}
+	if dryRuns && supportsDryRun(cmd) {
+		w := colorprofile.NewWriter(root.ErrOrStderr(), os.Environ())
+		recorder.writeSummary(w, stylesFn())
+	}
 	return nil
 }
 
+// execute executes the given root command, recovering from panics if
+// [WithCrashReports] is set, and returns the command that was run.
+func (s settings) execute(ctx context.Context, root *cobra.Command, inv *invocation) (cmd *cobra.Command, err error) {
+	if s.crashReports {
+		defer func() {
+			if r := recover(); r != nil {
+				cmd, err = root, crashError(root, inv, r, debug.Stack(), buildVersion(s), newRedactor(root, s.redactPatterns))
+			}
+		}()
+	}
+	cmd, err = root.ExecuteContextC(ctx)
+	if cmd == nil {
+		cmd = root
+	}
+	return cmd, err //nolint:wrapcheck
+}
+
+// styles returns the styles for the given colorscheme, customized by the
+// [StylesFunc], if any.
+func (s settings) styles(cs ColorScheme) Styles {
+	styles := makeStyles(cs)
+	if s.stylesFunc != nil {
+		styles = s.stylesFunc(styles)
+	}
+	styles.accessible = s.accessible
+	styles.hyperlinks = s.hyperlinks
+	if len(s.redactPatterns) > 0 {
+		styles.redact = newRedactor(nil, s.redactPatterns).redact
+	}
+	return styles
+}
+
 func buildVersion(opts settings) string {
 	commit := opts.commit
 	version := opts.version
@@ -2,11 +2,18 @@ package fang_test
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
 	"testing"
 
 	"charm.land/fang/v2"
+	"charm.land/lipgloss/v2"
 	"github.com/charmbracelet/x/exp/golden"
 	"github.com/spf13/cobra"
 	"github.com/stretchr/testify/require"
@@ -280,6 +287,604 @@ echo 'foo' |
 		exercise(t, mkroot)
 	})
 
+	t.Run("with styles func", func(t *testing.T) {
+		mkroot := func() *cobra.Command {
+			cmd := &cobra.Command{
+				Use:   "simple",
+				Short: "Short help",
+			}
+			cmd.Flags().String("name", "", "name of the thing")
+			cmd.AddCommand(&cobra.Command{
+				Use:   "sub",
+				Short: "sub does things",
+			})
+			return cmd
+		}
+		exercise(t, mkroot, fang.WithStylesFunc(func(s fang.Styles) fang.Styles {
+			s.Title = s.Title.UnsetTransform().Padding(0)
+			s.FlagDescription = s.FlagDescription.UnsetTransform()
+			s.ErrorText = s.ErrorText.UnsetTransform()
+			s.ErrorHeader = s.ErrorHeader.SetString("Oops")
+			return s
+		}))
+	})
+
+	t.Run("help fallback", func(t *testing.T) {
+		doExercise(
+			t,
+			toMkroot(&cobra.Command{Use: "simple", Short: "Short help"}),
+			[]string{"--help"},
+			assertNoError,
+			fang.WithStylesFunc(func(fang.Styles) fang.Styles {
+				panic("oops")
+			}),
+		)
+	})
+
+	t.Run("accessible", func(t *testing.T) {
+		mkroot := func() *cobra.Command {
+			cmd := &cobra.Command{
+				Use:   "simple [args]",
+				Short: "Short help",
+				Example: `
+# Run it:
+simple --name=Carlos sub "quoted value"
+`,
+			}
+			cmd.AddGroup(&cobra.Group{
+				ID:    "group",
+				Title: "My group",
+			})
+			cmd.Flags().StringP("name", "n", "jane", "the name")
+			cmd.Flags().Bool("yes", false, "assume yes")
+			cmd.AddCommand(&cobra.Command{
+				Use:     "sub [args]",
+				Short:   "a sub command",
+				GroupID: "group",
+			})
+			return cmd
+		}
+		exercise(t, mkroot, fang.WithAccessible())
+
+		t.Run("env", func(t *testing.T) {
+			t.Setenv("ACCESSIBLE", "1")
+			doExercise(t, mkroot, []string{"--help"}, assertNoError)
+		})
+	})
+
+	t.Run("with shell examples", func(t *testing.T) {
+		mkroot := func() *cobra.Command {
+			cmd := &cobra.Command{
+				Use:   "example",
+				Short: "Short help",
+				Example: `
+# Quoted operators and escaped quotes:
+echo "a | b; c" | example sub --msg="say \"hi\""
+
+# Substitutions, variables and empty assignments:
+FOO= BAR=$HOME example --at=$(date +%s) "$USER" # trailing comment
+
+# Multiple commands:
+example sub; example sub another && echo done
+
+# Heredocs:
+example sub <<EOF
+hello $name
+EOF
+			`,
+			}
+			sub := &cobra.Command{
+				Use:   "sub",
+				Short: "a sub command",
+			}
+			cmd.AddCommand(sub)
+			sub.AddCommand(&cobra.Command{
+				Use:   "another",
+				Short: "another sub command",
+			})
+			cmd.Flags().String("at", "", "the time")
+			return cmd
+		}
+		exercise(t, mkroot)
+	})
+
+	t.Run("with docs links", func(t *testing.T) {
+		mkroot := func() *cobra.Command {
+			return &cobra.Command{
+				Use:         "deploy",
+				Short:       "Deploy the app",
+				Annotations: map[string]string{fang.DocsURLAnnotation: "https://example.com/docs/deploy"},
+				RunE: func(*cobra.Command, []string) error {
+					return fmt.Errorf("could not deploy: %w", runbookError{})
+				},
+			}
+		}
+		t.Run("help", func(t *testing.T) {
+			doExercise(t, mkroot, []string{"--help"}, assertNoError)
+		})
+		t.Run("error", func(t *testing.T) {
+			doExercise(t, mkroot, []string{}, assertError)
+		})
+		t.Run("rich error", func(t *testing.T) {
+			doExercise(t, toMkroot(&cobra.Command{
+				Use: "deploy",
+				RunE: func(*cobra.Command, []string) error {
+					return &fang.Error{Err: runbookError{}, Hint: "Retry in a minute."}
+				},
+			}), []string{}, assertError)
+		})
+	})
+
+	t.Run("with rich error", func(t *testing.T) {
+		mkroot := func() *cobra.Command {
+			return &cobra.Command{
+				Use:   "deploy",
+				Short: "Deploy the app",
+				RunE: func(*cobra.Command, []string) error {
+					return fmt.Errorf("could not deploy: %w", &fang.Error{
+						Err:     errors.New("session expired"),
+						Title:   "Not logged in",
+						Details: "Deploying requires a session, which expired 2 days ago.",
+						Hint:    "Log in again and retry:",
+						Command: "deploy login --sso 'my org'",
+						URL:     "https://example.com/docs/login",
+					})
+				},
+			}
+		}
+		t.Run("styled", func(t *testing.T) {
+			doExercise(t, mkroot, []string{}, assertError)
+		})
+		t.Run("accessible", func(t *testing.T) {
+			doExercise(t, mkroot, []string{}, assertError, fang.WithAccessible())
+		})
+	})
+
+	t.Run("with error chains", func(t *testing.T) {
+		mkroot := func() *cobra.Command {
+			return &cobra.Command{
+				Use:   "batch",
+				Short: "Process a batch of files",
+				RunE: func(*cobra.Command, []string) error {
+					return fmt.Errorf("2 of 3 files failed: %w", errors.Join(
+						fmt.Errorf("could not process a.txt: %w", fmt.Errorf("open a.txt: %w", os.ErrPermission)),
+						errors.New("b.txt is empty"),
+					))
+				},
+			}
+		}
+		t.Run("styled", func(t *testing.T) {
+			doExercise(t, mkroot, []string{}, assertError)
+		})
+		t.Run("accessible", func(t *testing.T) {
+			doExercise(t, mkroot, []string{}, assertError, fang.WithAccessible())
+		})
+	})
+
+	t.Run("with json errors", func(t *testing.T) {
+		mkroot := func() *cobra.Command {
+			root := &cobra.Command{Use: "app"}
+			root.AddCommand(&cobra.Command{
+				Use: "deploy",
+				RunE: func(*cobra.Command, []string) error {
+					return fmt.Errorf("could not deploy: %w", &fang.Error{
+						Err:     errors.Join(errors.New("session expired"), context.Canceled),
+						Title:   "Not logged in",
+						Hint:    "Log in again and retry:",
+						Command: "app login",
+						URL:     "https://example.com/docs/login",
+					})
+				},
+			})
+			get := &cobra.Command{
+				Use:  "get <id>",
+				Args: cobra.ExactArgs(1),
+				Run:  func(*cobra.Command, []string) {},
+			}
+			get.Flags().String("output", "", "Output format")
+			_ = get.MarkFlagRequired("output")
+			root.AddCommand(get)
+			return root
+		}
+		t.Run("usage", func(t *testing.T) {
+			doExercise(t, mkroot, []string{"deploy", "--nope"}, assertError, fang.WithJSONErrors())
+		})
+		t.Run("arg count", func(t *testing.T) {
+			doExercise(t, mkroot, []string{"get"}, assertError, fang.WithJSONErrors())
+		})
+		t.Run("required flag", func(t *testing.T) {
+			doExercise(t, mkroot, []string{"get", "10"}, assertError, fang.WithJSONErrors())
+		})
+		t.Run("runtime", func(t *testing.T) {
+			t.Setenv("FANG_ERROR_FORMAT", "json")
+			doExercise(t, mkroot, []string{"deploy"}, assertError)
+		})
+	})
+
+	t.Run("with sensitive flags", func(t *testing.T) {
+		mkroot := func() *cobra.Command {
+			cmd := &cobra.Command{
+				Use:   "login",
+				Short: "Log in",
+				RunE: func(cmd *cobra.Command, _ []string) error {
+					token, _ := cmd.Flags().GetString("token")
+					return fmt.Errorf("token %q was rejected with session sess_0123", token)
+				},
+			}
+			cmd.Flags().String("token", "s3cr3t-token", "API token")
+			_ = fang.MarkFlagSensitive(cmd.Flags(), "token")
+			return cmd
+		}
+		options := []fang.Option{
+			fang.WithRedactPatterns(regexp.MustCompile(`sess_\w+`)),
+		}
+		t.Run("help", func(t *testing.T) {
+			doExercise(t, mkroot, []string{"--help"}, assertNoError, options...)
+		})
+		t.Run("error", func(t *testing.T) {
+			doExercise(t, mkroot, []string{"--token", "other-token"}, assertError, options...)
+		})
+		t.Run("custom error handler", func(t *testing.T) {
+			doExercise(t, mkroot, []string{"--token", "other-token"}, assertError, append(
+				options,
+				fang.WithErrorHandler(func(w io.Writer, styles fang.Styles, err error) {
+					_, _ = fmt.Fprintln(w, "Oops:", styles.Redact(err.Error()))
+				}),
+			)...)
+		})
+	})
+
+	t.Run("with env", func(t *testing.T) {
+		mkroot := func() *cobra.Command {
+			root := &cobra.Command{
+				Use:   "app",
+				Short: "An app configured by env",
+				PersistentPreRunE: func(cmd *cobra.Command, _ []string) error {
+					region, _ := cmd.Flags().GetString("region")
+					fmt.Fprintln(cmd.OutOrStdout(), "pre-run region:", region)
+					return nil
+				},
+			}
+			root.PersistentFlags().String("region", "eu", "Region to deploy to")
+			deploy := &cobra.Command{
+				Use:   "deploy",
+				Short: "Deploy the app",
+				RunE: func(cmd *cobra.Command, _ []string) error {
+					for _, name := range []string{"region", "dry-run", "token", "replicas"} {
+						fmt.Fprintf(cmd.OutOrStdout(), "%s: %s\n", name, cmd.Flag(name).Value)
+					}
+					return nil
+				},
+			}
+			deploy.Flags().Bool("dry-run", false, "Only print what would be done")
+			deploy.Flags().String("token", "", "API token")
+			deploy.Flags().Int("replicas", 1, "Number of replicas")
+			_ = fang.BindFlagEnv(deploy.Flags(), "token", "GITHUB_TOKEN")
+			root.AddCommand(deploy)
+			return root
+		}
+		options := []fang.Option{fang.WithEnvPrefix("APP")}
+		t.Run("help", func(t *testing.T) {
+			doExercise(t, mkroot, []string{"deploy", "--help"}, assertNoError, options...)
+		})
+		t.Run("accessible help", func(t *testing.T) {
+			doExercise(t, mkroot, []string{"deploy", "--help"}, assertNoError, append(options, fang.WithAccessible())...)
+		})
+		t.Run("run", func(t *testing.T) {
+			t.Setenv("APP_REGION", "us")
+			t.Setenv("APP_DRY_RUN", "true")
+			t.Setenv("GITHUB_TOKEN", "t0k3n")
+			t.Setenv("APP_REPLICAS", "2")
+			doExercise(t, mkroot, []string{"deploy", "--replicas", "3"}, assertNoError, options...)
+		})
+		t.Run("invalid", func(t *testing.T) {
+			t.Setenv("APP_REPLICAS", "many")
+			doExercise(t, mkroot, []string{"deploy"}, assertError, options...)
+		})
+		t.Run("required", func(t *testing.T) {
+			t.Setenv("GITHUB_TOKEN", "t0k3n")
+			doExercise(t, func() *cobra.Command {
+				root := mkroot()
+				deploy, _, _ := root.Find([]string{"deploy"})
+				_ = deploy.MarkFlagRequired("token")
+				return root
+			}, []string{"deploy"}, assertNoError, options...)
+		})
+	})
+
+	t.Run("with prompts", func(t *testing.T) {
+		mkroot := func(input string) func() *cobra.Command {
+			return func() *cobra.Command {
+				root := &cobra.Command{Use: "app", Short: "An app asking for what it needs"}
+				greet := &cobra.Command{
+					Use:   "greet <name>",
+					Short: "Greet someone",
+					Args:  cobra.ExactArgs(1),
+					RunE: func(cmd *cobra.Command, args []string) error {
+						fmt.Fprintln(cmd.OutOrStdout(), "name:", args[0])
+						for _, name := range []string{"greeting", "style", "loud"} {
+							fmt.Fprintf(cmd.OutOrStdout(), "%s: %s\n", name, cmd.Flag(name).Value)
+						}
+						return nil
+					},
+				}
+				greet.Flags().String("greeting", "", "Greeting to use")
+				greet.Flags().String("style", "", "Style of the greeting")
+				greet.Flags().Bool("loud", false, "Shout the greeting")
+				_ = fang.MarkFlagEnum(greet.Flags(), "style", "plain", "fancy")
+				for _, name := range []string{"greeting", "style", "loud"} {
+					_ = greet.MarkFlagRequired(name)
+				}
+				root.AddCommand(greet)
+				root.SetIn(strings.NewReader(input))
+				return root
+			}
+		}
+		assertPrompts := func(t *testing.T, err error, stdout, stderr bytes.Buffer) {
+			t.Helper()
+			require.NoError(t, err, stderr.String())
+			golden.RequireEqual(t, append(stderr.Bytes(), stdout.Bytes()...))
+		}
+		options := []fang.Option{fang.WithPrompts()}
+		t.Run("interactive", func(t *testing.T) {
+			fang.SetInteractive(t, true)
+			doExercise(t, mkroot("bob\nhello\nmaybe\ny\n3\nfancy\n"), []string{"greet"}, assertPrompts, options...)
+		})
+		t.Run("partially set", func(t *testing.T) {
+			fang.SetInteractive(t, true)
+			doExercise(t, mkroot("\n1\n"), []string{"greet", "alice", "--greeting", "hi"}, assertPrompts, options...)
+		})
+		t.Run("no answer", func(t *testing.T) {
+			fang.SetInteractive(t, true)
+			doExercise(t, mkroot("bob\n"), []string{"greet"}, assertError, options...)
+		})
+		t.Run("not interactive", func(t *testing.T) {
+			fang.SetInteractive(t, false)
+			doExercise(t, mkroot("bob\n"), []string{"greet"}, assertError, options...)
+		})
+	})
+
+	t.Run("with destructive command", func(t *testing.T) {
+		mkroot := func(input string) func() *cobra.Command {
+			return func() *cobra.Command {
+				root := &cobra.Command{Use: "app", Short: "An app managing databases"}
+				drop := &cobra.Command{
+					Use:         "drop <database>",
+					Short:       "Drop a database",
+					Args:        cobra.ExactArgs(1),
+					Annotations: map[string]string{fang.DestructiveAnnotation: "true"},
+					RunE: func(cmd *cobra.Command, args []string) error {
+						fmt.Fprintln(cmd.OutOrStdout(), "dropped", args[0])
+						return nil
+					},
+				}
+				list := &cobra.Command{
+					Use:   "list",
+					Short: "List the databases",
+					Run:   func(*cobra.Command, []string) {},
+				}
+				root.AddCommand(drop, list)
+				root.SetIn(strings.NewReader(input))
+				return root
+			}
+		}
+		assertConfirm := func(t *testing.T, err error, stdout, stderr bytes.Buffer) {
+			t.Helper()
+			require.NoError(t, err, stderr.String())
+			golden.RequireEqual(t, append(stderr.Bytes(), stdout.Bytes()...))
+		}
+		t.Run("root help", func(t *testing.T) {
+			doExercise(t, mkroot(""), []string{"--help"}, assertNoError)
+		})
+		t.Run("help", func(t *testing.T) {
+			doExercise(t, mkroot(""), []string{"drop", "--help"}, assertNoError)
+		})
+		t.Run("accessible help", func(t *testing.T) {
+			doExercise(t, mkroot(""), []string{"drop", "--help"}, assertNoError, fang.WithAccessible())
+		})
+		t.Run("confirmed", func(t *testing.T) {
+			fang.SetInteractive(t, true)
+			doExercise(t, mkroot("y\n"), []string{"drop", "prod"}, assertConfirm)
+		})
+		t.Run("declined", func(t *testing.T) {
+			fang.SetInteractive(t, true)
+			doExercise(t, mkroot("\n"), []string{"drop", "prod"}, assertError)
+		})
+		t.Run("yes", func(t *testing.T) {
+			fang.SetInteractive(t, false)
+			doExercise(t, mkroot(""), []string{"drop", "prod", "-y"}, assertNoError)
+		})
+		t.Run("not interactive", func(t *testing.T) {
+			fang.SetInteractive(t, false)
+			doExercise(t, mkroot(""), []string{"drop", "prod"}, assertError)
+		})
+		t.Run("inherited shorthand", func(t *testing.T) {
+			fang.SetInteractive(t, false)
+			mkroot := func() *cobra.Command {
+				root := mkroot("")()
+				root.PersistentFlags().BoolP("yaml", "y", false, "Output YAML")
+				return root
+			}
+			doExercise(t, mkroot, []string{"drop", "--help"}, assertNoError)
+			doExercise(t, mkroot, []string{"drop", "prod", "--yes"}, func(t *testing.T, err error, stdout, stderr bytes.Buffer) {
+				t.Helper()
+				require.NoError(t, err, stderr.String())
+				require.Equal(t, "dropped prod\n", stdout.String())
+			})
+		})
+		t.Run("inherited flag", func(t *testing.T) {
+			fang.SetInteractive(t, false)
+			mkroot := func() *cobra.Command {
+				root := mkroot("")()
+				root.PersistentFlags().Bool("yes", false, "Answer yes to everything")
+				return root
+			}
+			doExercise(t, mkroot, []string{"drop", "--help"}, assertNoError)
+			doExercise(t, mkroot, []string{"drop", "prod", "--yes"}, func(t *testing.T, err error, stdout, stderr bytes.Buffer) {
+				t.Helper()
+				require.NoError(t, err, stderr.String())
+				require.Equal(t, "dropped prod\n", stdout.String())
+			})
+		})
+	})
+
+	t.Run("with dry run", func(t *testing.T) {
+		mkroot := func() *cobra.Command {
+			root := &cobra.Command{Use: "app", Short: "An app cleaning up"}
+			clean := &cobra.Command{
+				Use:   "clean",
+				Short: "Remove the old releases",
+				Annotations: map[string]string{
+					fang.DestructiveAnnotation: "true",
+					fang.DryRunAnnotation:      "true",
+				},
+				RunE: func(cmd *cobra.Command, _ []string) error {
+					for _, release := range []string{"v1.0.0", "v1.1.0"} {
+						if fang.IsDryRun(cmd.Context()) {
+							fang.RecordAction(cmd.Context(), "Remove release %s", release)
+							continue
+						}
+						fmt.Fprintln(cmd.OutOrStdout(), "removed", release)
+					}
+					return nil
+				},
+			}
+			drop := &cobra.Command{
+				Use:         "drop",
+				Short:       "Drop the database",
+				Annotations: map[string]string{fang.DestructiveAnnotation: "true"},
+				RunE: func(cmd *cobra.Command, _ []string) error {
+					fmt.Fprintln(cmd.OutOrStdout(), "dropped")
+					return nil
+				},
+			}
+			root.AddCommand(clean, drop)
+			return root
+		}
+		assertSummary := func(t *testing.T, err error, stdout, stderr bytes.Buffer) {
+			t.Helper()
+			require.NoError(t, err)
+			golden.RequireEqual(t, []byte(stdout.String()+"\n---\n"+stderr.String()))
+		}
+		options := []fang.Option{fang.WithDryRun()}
+		t.Run("help", func(t *testing.T) {
+			doExercise(t, mkroot, []string{"clean", "--help"}, assertNoError, options...)
+		})
+		t.Run("run", func(t *testing.T) {
+			fang.SetInteractive(t, false)
+			doExercise(t, mkroot, []string{"clean", "--dry-run"}, assertSummary, options...)
+		})
+		t.Run("accessible", func(t *testing.T) {
+			fang.SetInteractive(t, false)
+			doExercise(t, mkroot, []string{"clean", "--dry-run"}, assertSummary, append(options, fang.WithAccessible())...)
+		})
+		t.Run("no actions", func(t *testing.T) {
+			doExercise(t, toMkroot(&cobra.Command{
+				Use:         "noop",
+				Annotations: map[string]string{fang.DryRunAnnotation: "true"},
+				Run:         func(*cobra.Command, []string) {},
+			}), []string{"--dry-run"}, assertSummary, options...)
+		})
+		t.Run("without flag", func(t *testing.T) {
+			doExercise(t, mkroot, []string{"clean", "--yes"}, assertSummary, options...)
+		})
+		t.Run("inherited flag", func(t *testing.T) {
+			fang.SetInteractive(t, false)
+			doExercise(t, func() *cobra.Command {
+				root := mkroot()
+				root.PersistentFlags().Bool("dry-run", false, "Change nothing")
+				return root
+			}, []string{"clean", "--dry-run"}, assertSummary, options...)
+		})
+		t.Run("unsupported", func(t *testing.T) {
+			fang.SetInteractive(t, false)
+			doExercise(t, mkroot, []string{"drop", "--dry-run"}, func(t *testing.T, err error, stdout, _ bytes.Buffer) {
+				t.Helper()
+				require.ErrorContains(t, err, "unknown flag: --dry-run")
+				require.Empty(t, stdout.String())
+			}, options...)
+		})
+		t.Run("unsupported with own flag", func(t *testing.T) {
+			fang.SetInteractive(t, false)
+			doExercise(t, func() *cobra.Command {
+				root := mkroot()
+				drop, _, _ := root.Find([]string{"drop"})
+				drop.Flags().Bool("dry-run", false, "Ignored")
+				return root
+			}, []string{"drop", "--dry-run"}, func(t *testing.T, err error, stdout, stderr bytes.Buffer) {
+				t.Helper()
+				require.ErrorContains(t, err, "without a confirmation")
+				require.Empty(t, stdout.String())
+				require.NotContains(t, stderr.String(), "Nothing was done")
+			}, options...)
+		})
+	})
+
+	t.Run("with config", func(t *testing.T) {
+		mkroot := func() *cobra.Command {
+			root := &cobra.Command{Use: "app", Short: "An app configured by a file"}
+			root.PersistentFlags().String("region", "eu", "Region to deploy to")
+			deploy := &cobra.Command{
+				Use:   "deploy",
+				Short: "Deploy the app",
+				RunE: func(cmd *cobra.Command, _ []string) error {
+					for _, name := range []string{"region", "replicas", "tags", "token"} {
+						fmt.Fprintf(cmd.OutOrStdout(), "%s: %s\n", name, cmd.Flag(name).Value)
+					}
+					return nil
+				},
+			}
+			deploy.Flags().Int("replicas", 1, "Number of replicas")
+			deploy.Flags().StringSlice("tags", nil, "Tags of the deployment")
+			deploy.Flags().String("token", "", "API token")
+			_ = fang.MarkFlagSensitive(deploy.Flags(), "token")
+			root.AddCommand(deploy)
+			return root
+		}
+		options := []fang.Option{fang.WithConfig(), fang.WithEnvPrefix("APP")}
+		for _, format := range []string{"toml", "yaml", "json"} {
+			t.Run(format, func(t *testing.T) {
+				doExercise(t, mkroot, []string{"deploy", "--config", "testdata/config/config." + format}, assertNoError, options...)
+			})
+		}
+		t.Run("xdg", func(t *testing.T) {
+			dir := t.TempDir()
+			t.Setenv("XDG_CONFIG_HOME", dir)
+			bts, err := os.ReadFile("testdata/config/config.toml")
+			require.NoError(t, err)
+			require.NoError(t, os.MkdirAll(filepath.Join(dir, "app"), 0o755))
+			require.NoError(t, os.WriteFile(filepath.Join(dir, "app", "config.toml"), bts, 0o644))
+			doExercise(t, mkroot, []string{"deploy"}, assertNoError, options...)
+		})
+		t.Run("precedence", func(t *testing.T) {
+			t.Setenv("APP_REPLICAS", "3")
+			t.Setenv("APP_TOKEN", "t0k3n")
+			doExercise(t, mkroot, []string{"deploy", "--config", "testdata/config/config.toml", "--region", "ap"}, assertNoError, options...)
+		})
+		t.Run("show config", func(t *testing.T) {
+			t.Setenv("APP_REPLICAS", "3")
+			t.Setenv("APP_TOKEN", "t0k3n")
+			doExercise(t, mkroot, []string{"deploy", "--config", "testdata/config/config.toml", "--region", "ap", "--show-config"}, assertNoError, options...)
+		})
+		t.Run("large int", func(t *testing.T) {
+			doExercise(t, mkroot, []string{"deploy", "--config", "testdata/config/large.json"}, assertNoError, options...)
+		})
+		t.Run("large int show config", func(t *testing.T) {
+			doExercise(t, mkroot, []string{"deploy", "--config", "testdata/config/large.json", "--show-config"}, assertNoError, options...)
+		})
+		t.Run("unknown key", func(t *testing.T) {
+			doExercise(t, mkroot, []string{"deploy", "--config", "testdata/config/unknown.toml"}, assertError, options...)
+		})
+		t.Run("required", func(t *testing.T) {
+			doExercise(t, func() *cobra.Command {
+				root := mkroot()
+				deploy, _, _ := root.Find([]string{"deploy"})
+				_ = deploy.MarkFlagRequired("replicas")
+				return root
+			}, []string{"deploy", "--config", "testdata/config/config.toml"}, assertNoError, options...)
+		})
+	})
+
 	t.Run("with multiline flag descriptions", func(t *testing.T) {
 		mkroot := func() *cobra.Command {
 			cmd := &cobra.Command{
@@ -299,6 +904,308 @@ Refer to https://docs.docker.com/go/formatting/ for more information about forma
 	})
 }
 
+func TestThemeCommand(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	mkroot := func() *cobra.Command {
+		return &cobra.Command{
+			Use:   "simple",
+			Short: "Short help",
+			Run:   func(*cobra.Command, []string) {},
+		}
+	}
+	options := []fang.Option{
+		fang.WithThemeCommand(),
+		fang.WithNamedColorScheme("mine", fang.AnsiColorScheme),
+	}
+
+	t.Run("list", func(t *testing.T) {
+		doExercise(t, mkroot, []string{"theme"}, assertNoError, options...)
+	})
+
+	t.Run("preview", func(t *testing.T) {
+		doExercise(t, mkroot, []string{"theme", "preview", "ansi"}, assertNoError, options...)
+	})
+
+	t.Run("preview unknown", func(t *testing.T) {
+		doExercise(t, mkroot, []string{"theme", "preview", "nope"}, assertError, options...)
+	})
+
+	t.Run("set", func(t *testing.T) {
+		doExercise(
+			t, mkroot,
+			[]string{"theme", "set", "mine"},
+			func(t *testing.T, err error, stdout, _ bytes.Buffer) {
+				t.Helper()
+				require.NoError(t, err)
+				require.Contains(t, stdout.String(), "Theme set to mine")
+			},
+			options...,
+		)
+		t.Run("list", func(t *testing.T) {
+			doExercise(t, mkroot, []string{"theme"}, assertNoError, options...)
+		})
+	})
+
+	t.Run("reset", func(t *testing.T) {
+		doExercise(t, mkroot, []string{"theme", "reset"}, assertNoError, options...)
+		t.Run("list", func(t *testing.T) {
+			doExercise(t, mkroot, []string{"theme"}, assertNoError, options...)
+		})
+	})
+
+	t.Run("with config", func(t *testing.T) {
+		dir := t.TempDir()
+		t.Setenv("XDG_CONFIG_HOME", dir)
+		options := append(options, fang.WithConfig())
+		path := filepath.Join(dir, "simple", "config.toml")
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "simple", "theme"), []byte("ansi\n"), 0o644))
+
+		doExercise(t, mkroot, []string{"theme", "set", "mine"}, func(t *testing.T, err error, _, _ bytes.Buffer) {
+			t.Helper()
+			require.NoError(t, err)
+		}, options...)
+		bts, err := os.ReadFile(path)
+		require.NoError(t, err)
+		require.Equal(t, "theme = \"mine\"\n", string(bts))
+		require.NoFileExists(t, filepath.Join(dir, "simple", "theme"))
+
+		doExercise(t, mkroot, []string{}, func(t *testing.T, err error, _, _ bytes.Buffer) {
+			t.Helper()
+			require.NoError(t, err)
+		}, options...)
+
+		doExercise(t, mkroot, []string{"theme", "reset"}, func(t *testing.T, err error, _, _ bytes.Buffer) {
+			t.Helper()
+			require.NoError(t, err)
+		}, options...)
+		bts, err = os.ReadFile(path)
+		require.NoError(t, err)
+		require.Empty(t, string(bts))
+	})
+
+	t.Run("with config flag", func(t *testing.T) {
+		t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+		path := filepath.Join(t.TempDir(), "custom.toml")
+		require.NoError(t, os.WriteFile(path, nil, 0o600))
+		var used bool
+		options := append(
+			options,
+			fang.WithConfig(),
+			fang.WithNamedColorScheme("traced", func(c lipgloss.LightDarkFunc) fang.ColorScheme {
+				used = true
+				return fang.AnsiColorScheme(c)
+			}),
+		)
+		assertUsed := func(want bool) func(*testing.T, error, bytes.Buffer, bytes.Buffer) {
+			return func(t *testing.T, err error, _, stderr bytes.Buffer) {
+				t.Helper()
+				require.NoError(t, err, stderr.String())
+				require.Equal(t, want, used)
+			}
+		}
+
+		doExercise(t, mkroot, []string{"theme", "set", "traced", "--config", path}, assertUsed(false), options...)
+		bts, err := os.ReadFile(path)
+		require.NoError(t, err)
+		require.Equal(t, "theme = \"traced\"\n", string(bts))
+
+		for _, args := range [][]string{
+			{"--config", path, "--help"},
+			{"--config", path, "--show-config"},
+		} {
+			used = false
+			doExercise(t, mkroot, args, assertUsed(true), options...)
+		}
+		used = false
+		doExercise(t, mkroot, []string{"--help"}, assertUsed(false), options...)
+	})
+}
+
+func TestCrashReports(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_STATE_HOME", dir)
+	mkroot := func() *cobra.Command {
+		root := &cobra.Command{
+			Use:  "crashy",
+			Args: cobra.ArbitraryArgs,
+			RunE: func(*cobra.Command, []string) error {
+				panic("boom")
+			},
+		}
+		root.Flags().String("name", "", "Name")
+		root.Flags().String("token", "", "Token")
+		root.Flags().Bool("force", false, "Force")
+		return root
+	}
+	args := []string{"--name", "jane", "--token", "t0k3n", "--force", "arg"}
+	doExercise(
+		t, mkroot, args,
+		func(t *testing.T, err error, _, stderr bytes.Buffer) {
+			t.Helper()
+			var perr *fang.PanicError
+			require.ErrorAs(t, err, &perr)
+			require.Equal(t, "boom", perr.Value)
+			require.NotEmpty(t, perr.Report)
+			require.Contains(t, stderr.String(), "Unexpected error")
+			require.Contains(t, stderr.String(), filepath.Base(perr.Report))
+
+			report, err := os.ReadFile(perr.Report)
+			require.NoError(t, err)
+			require.Contains(t, string(report), "crashy crash report")
+			require.Contains(t, string(report), "Version: unknown (built from source)")
+			require.Contains(t, string(report), "Args:    crashy --force --name=jane --token=*** -- arg\n")
+			require.Contains(t, string(report), "panic: boom")
+			require.Contains(t, string(report), "goroutine")
+		},
+		fang.WithCrashReports(),
+	)
+
+	// crashes in the same second get their own reports.
+	doExercise(t, mkroot, args, func(t *testing.T, err error, _, _ bytes.Buffer) {
+		t.Helper()
+		require.Error(t, err)
+	}, fang.WithCrashReports())
+	reports, err := os.ReadDir(filepath.Join(dir, "crashy"))
+	require.NoError(t, err)
+	require.Len(t, reports, 2)
+}
+
+func TestConfigCommand(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	t.Setenv("XDG_CONFIG_DIRS", filepath.Join(dir, "none"))
+	mkroot := func() *cobra.Command {
+		root := &cobra.Command{Use: "app", Short: "An app configured by a file"}
+		root.PersistentFlags().String("region", "eu", "Region to deploy to")
+		deploy := &cobra.Command{
+			Use:   "deploy",
+			Short: "Deploy the app",
+			RunE: func(cmd *cobra.Command, _ []string) error {
+				for _, name := range []string{"region", "replicas", "tags"} {
+					fmt.Fprintf(cmd.OutOrStdout(), "%s: %s\n", name, cmd.Flag(name).Value)
+				}
+				return nil
+			},
+		}
+		deploy.Flags().Int("replicas", 1, "Number of replicas")
+		deploy.Flags().StringSlice("tags", nil, "Tags of the deployment")
+		root.AddCommand(deploy)
+		return root
+	}
+	options := []fang.Option{fang.WithConfigCommand(), fang.WithEnvPrefix("APP")}
+	assertOutput := func(t *testing.T, err error, stdout, stderr bytes.Buffer) {
+		t.Helper()
+		require.NoError(t, err, stderr.String())
+		golden.RequireEqual(t, []byte(strings.ReplaceAll(stdout.String(), dir, "$XDG_CONFIG_HOME")))
+	}
+
+	t.Run("path", func(t *testing.T) {
+		doExercise(t, mkroot, []string{"config", "path"}, assertOutput, options...)
+	})
+	t.Run("set", func(t *testing.T) {
+		doExercise(t, mkroot, []string{"config", "set", "deploy.replicas", "3"}, assertOutput, options...)
+		t.Run("slice", func(t *testing.T) {
+			doExercise(t, mkroot, []string{"config", "set", "deploy.tags", "web,blue"}, assertOutput, options...)
+		})
+		t.Run("inherited", func(t *testing.T) {
+			doExercise(t, mkroot, []string{"config", "set", "deploy.region", "us"}, assertOutput, options...)
+		})
+		t.Run("file", func(t *testing.T) {
+			bts, err := os.ReadFile(filepath.Join(dir, "app", "config.toml"))
+			require.NoError(t, err)
+			golden.RequireEqual(t, bts)
+		})
+		t.Run("run", func(t *testing.T) {
+			doExercise(t, mkroot, []string{"deploy"}, assertNoError, options...)
+		})
+	})
+	t.Run("set unknown key", func(t *testing.T) {
+		doExercise(t, mkroot, []string{"config", "set", "deploy.replica", "3"}, assertError, options...)
+	})
+	t.Run("set invalid value", func(t *testing.T) {
+		doExercise(t, mkroot, []string{"config", "set", "deploy.replicas", "many"}, assertError, options...)
+	})
+	t.Run("get", func(t *testing.T) {
+		doExercise(t, mkroot, []string{"config", "get", "deploy.replicas"}, assertOutput, options...)
+		t.Run("default", func(t *testing.T) {
+			doExercise(t, mkroot, []string{"config", "get", "region"}, assertOutput, options...)
+		})
+	})
+	t.Run("stdout", func(t *testing.T) {
+		for _, args := range [][]string{
+			{"config", "get", "deploy.replicas"},
+			{"config", "path"},
+		} {
+			root := mkroot()
+			var stderr bytes.Buffer
+			root.SetErr(&stderr)
+			root.SetArgs(args)
+
+			r, w, err := os.Pipe()
+			require.NoError(t, err)
+			stdout := os.Stdout
+			os.Stdout = w
+			err = fang.Execute(t.Context(), root, options...)
+			os.Stdout = stdout
+			require.NoError(t, w.Close())
+			out, _ := io.ReadAll(r)
+
+			require.NoError(t, err, stderr.String())
+			require.NotEmpty(t, string(out))
+			require.Empty(t, stderr.String())
+		}
+	})
+	t.Run("list", func(t *testing.T) {
+		t.Setenv("APP_REGION", "ap")
+		doExercise(t, mkroot, []string{"config", "list"}, assertOutput, options...)
+	})
+	t.Run("unset", func(t *testing.T) {
+		doExercise(t, mkroot, []string{"config", "unset", "deploy.replicas"}, assertOutput, options...)
+		t.Run("again", func(t *testing.T) {
+			doExercise(t, mkroot, []string{"config", "unset", "deploy.replicas"}, assertOutput, options...)
+		})
+	})
+	t.Run("edit", func(t *testing.T) {
+		editor := filepath.Join(t.TempDir(), "editor")
+		require.NoError(t, os.WriteFile(editor, []byte("#!/bin/sh\necho 'nope = 1' >> \"$1\"\n"), 0o755))
+		t.Setenv("VISUAL", "")
+		t.Setenv("EDITOR", editor)
+		doExercise(
+			t, mkroot,
+			[]string{"config", "edit"},
+			func(t *testing.T, err error, _, stderr bytes.Buffer) {
+				t.Helper()
+				require.Error(t, err)
+				require.Contains(t, stderr.String(), `unknown key "deploy.nope"`)
+			},
+			options...,
+		)
+	})
+	t.Run("set with comments", func(t *testing.T) {
+		path := filepath.Join(dir, "app", "config.toml")
+		content := []byte("# where to deploy\nregion = \"us\"\n")
+		require.NoError(t, os.WriteFile(path, content, 0o600))
+		doExercise(
+			t, mkroot,
+			[]string{"config", "set", "deploy.replicas", "3"},
+			func(t *testing.T, err error, _, _ bytes.Buffer) {
+				t.Helper()
+				require.ErrorContains(t, err, "as its comments would be lost")
+			},
+			options...,
+		)
+		bts, err := os.ReadFile(path)
+		require.NoError(t, err)
+		require.Equal(t, content, bts)
+	})
+}
+
+type runbookError struct{}
+
+func (runbookError) Error() string   { return "the cluster is unreachable" }
+func (runbookError) DocsURL() string { return "https://example.com/runbooks/unreachable" }
+
 func exercise(t *testing.T, mkroot func() *cobra.Command, options ...fang.Option) {
 	t.Helper()
 
@@ -0,0 +1,110 @@
+package fang
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+type figSubcommand struct {
+	Name        any             `json:"name"`
+	Description string          `json:"description,omitempty"`
+	Subcommands []figSubcommand `json:"subcommands,omitempty"`
+	Options     []figOption     `json:"options,omitempty"`
+	Args        []figArg        `json:"args,omitempty"`
+}
+
+type figOption struct {
+	Name         any     `json:"name"`
+	Description  string  `json:"description,omitempty"`
+	Args         *figArg `json:"args,omitempty"`
+	IsPersistent bool    `json:"isPersistent,omitempty"`
+	IsRequired   bool    `json:"isRequired,omitempty"`
+	IsRepeatable bool    `json:"isRepeatable,omitempty"`
+}
+
+type figArg struct {
+	Name        string   `json:"name"`
+	Default     string   `json:"default,omitempty"`
+	IsOptional  bool     `json:"isOptional,omitempty"`
+	IsVariadic  bool     `json:"isVariadic,omitempty"`
+	Suggestions []string `json:"suggestions,omitempty"`
+}
+
+// WriteFigSpec writes the given command tree as a Fig completion spec, as
+// used by Amazon Q and https://github.com/withfig/autocomplete, including
+// aliases, positional arguments and the values declared with
+// [MarkFlagEnum] or ValidArgs.
+//
+// Hidden commands and flags are left out.
+func WriteFigSpec(w io.Writer, root *cobra.Command) error {
+	bts, err := json.MarshalIndent(figCommand(root), "", "  ")
+	if err != nil {
+		return fmt.Errorf("could not encode fig spec: %w", err)
+	}
+	_, err = fmt.Fprintf(w, "const completionSpec: Fig.Spec = %s;\n\nexport default completionSpec;\n", bts)
+	//nolint:wrapcheck
+	return err
+}
+
+func figCommand(c *cobra.Command) figSubcommand {
+	sub := figSubcommand{
+		Name:        figName(append([]string{c.Name()}, c.Aliases...)),
+		Description: c.Short,
+	}
+
+	persistent := c.PersistentFlags()
+	visitOwnFlags(c, func(f *pflag.Flag) {
+		if f.Hidden {
+			return
+		}
+		value, usage := flagValueName(f)
+		opt := figOption{
+			Name:         figName(flagNames(f)),
+			Description:  usage,
+			IsPersistent: persistent.Lookup(f.Name) == f,
+			IsRequired:   isRequired(f),
+			IsRepeatable: isRepeatable(f),
+		}
+		if value != "" {
+			opt.Args = &figArg{
+				Name:        value,
+				Suggestions: flagEnum(f),
+			}
+			if hasDefault(f) && !isSensitive(f) {
+				opt.Args.Default = f.DefValue
+			}
+		}
+		sub.Options = append(sub.Options, opt)
+	})
+
+	suggestions := argEnum(c)
+	for _, arg := range useArgs(c) {
+		sub.Args = append(sub.Args, figArg{
+			Name:        arg.Name,
+			IsOptional:  !arg.Required,
+			IsVariadic:  arg.Variadic,
+			Suggestions: suggestions,
+		})
+	}
+
+	for _, sc := range c.Commands() {
+		if !sc.IsAvailableCommand() {
+			continue
+		}
+		sub.Subcommands = append(sub.Subcommands, figCommand(sc))
+	}
+	return sub
+}
+
+// figName returns the name of a Fig subcommand or option: a string if there
+// is only one, a list otherwise.
+func figName(names []string) any {
+	if len(names) == 1 {
+		return names[0]
+	}
+	return names
+}
@@ -2,6 +2,7 @@ package fang
 
 import (
 	"cmp"
+	"errors"
 	"fmt"
 	"io"
 	"iter"
@@ -39,8 +40,38 @@ var width = sync.OnceValue(func() int {
 	return min(w, 120)
 })
 
+// safeHelpFn renders the help for the given command, reporting a panic while
+// doing so as an error.
+func safeHelpFn(c *cobra.Command, w *colorprofile.Writer, styles func() Styles) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("could not render help: %v", r)
+		}
+	}()
+	helpFn(c, w, styles())
+	return nil
+}
+
+// plainHelpFn renders cobra's default help for the given command.
+func plainHelpFn(c *cobra.Command, args []string) {
+	root := c.Root()
+	fn := root.HelpFunc()
+	root.SetHelpFunc(nil)
+	defer root.SetHelpFunc(fn)
+	c.HelpFunc()(c, args)
+}
+
 func helpFn(c *cobra.Command, w *colorprofile.Writer, styles Styles) {
+	if styles.accessible {
+		accessibleHelpFn(c, w)
+		return
+	}
 	writeLongShort(w, styles, cmp.Or(c.Long, c.Short))
+	if isDestructive(c) {
+		_, _ = fmt.Fprintln(w)
+		_, _ = fmt.Fprintln(w, strings.Repeat(" ", shortPad)+destructiveBadge(styles)+" "+
+			styles.FlagDefault.Render("Asks for a confirmation, unless --yes is set"))
+	}
 	usage := styleUsage(c, styles.Codeblock.Program, true)
 	examples := styleExamples(c, styles)
 
@@ -49,7 +80,7 @@ func helpFn(c *cobra.Command, w *colorprofile.Writer, styles Styles) {
 	for _, ex := range examples {
 		blockWidth = max(blockWidth, lipgloss.Width(ex))
 	}
-	blockWidth = min(width()-padding, blockWidth+padding)
+	blockWidth = min(styles.maxWidth()-padding, blockWidth+padding)
 	blockStyle := styles.Codeblock.Base.Width(blockWidth)
 
 	// if the color profile is ascii or notty, or if the block has no
@@ -74,7 +105,8 @@ func helpFn(c *cobra.Command, w *colorprofile.Writer, styles Styles) {
 	groups, groupKeys := evalGroups(c)
 	cmds, cmdKeys := evalCmds(c, styles)
 	flags, flagKeys := evalFlags(c, styles)
-	space := calculateSpace(cmdKeys, flagKeys)
+	envs, envKeys := evalEnv(c, styles)
+	space := calculateSpace(cmdKeys, append(flagKeys, envKeys...))
 
 	for _, groupID := range groupKeys {
 		group := cmds[groupID]
@@ -104,6 +136,21 @@ func helpFn(c *cobra.Command, w *colorprofile.Writer, styles Styles) {
 		})
 	}
 
+	if len(envs) > 0 {
+		renderGroup(w, styles, space, "environment", func(yield func(string, string) bool) {
+			for _, k := range envKeys {
+				if !yield(k, envs[k]) {
+					return
+				}
+			}
+		})
+	}
+
+	if url := c.Annotations[DocsURLAnnotation]; url != "" {
+		_, _ = fmt.Fprintln(w)
+		_, _ = fmt.Fprintln(w, strings.Repeat(" ", shortPad)+learnMore(styles, w.Profile, url))
+	}
+
 	_, _ = fmt.Fprintln(w)
 }
 
@@ -113,13 +160,79 @@ func DefaultErrorHandler(w io.Writer, styles Styles, err error) {
 		// if stderr is not a tty, simply print the error without any
 		// styling or going through an [ErrorHandler]:
 		if !term.IsTerminal(w.Fd()) {
-			_, _ = fmt.Fprintln(w, err.Error())
+			_, _ = fmt.Fprintln(w, styles.Redact(err.Error()))
+			for _, line := range plainGuidance(err) {
+				_, _ = fmt.Fprintln(w, styles.Redact(line))
+			}
 			return
 		}
 	}
-	_, _ = fmt.Fprintln(w, styles.ErrorHeader.String())
-	_, _ = fmt.Fprintln(w, styles.ErrorText.Render(err.Error()+"."))
+
+	var rich *Error
+	if errors.As(err, &rich) {
+		rich = &Error{
+			Title:   styles.Redact(rich.Title),
+			Details: styles.Redact(rich.Details),
+			Hint:    styles.Redact(rich.Hint),
+			Command: styles.Redact(rich.Command),
+		}
+	}
+
+	trace := errorTrace(err)
+	for i := range trace {
+		trace[i].text = styles.Redact(trace[i].text)
+	}
+	if styles.accessible {
+		lines := plainErrorTrace(trace)
+		if rich != nil && rich.Title != "" {
+			lines = append([]string{punctuate(rich.Title)}, lines...)
+		}
+		switch {
+		case len(lines) == 0, strings.HasPrefix(lines[0], "- "):
+			lines = append([]string{"Error:"}, lines...)
+		default:
+			lines[0] = "Error: " + lines[0]
+		}
+		for _, line := range lines {
+			_, _ = fmt.Fprintln(w, line)
+		}
+		for _, line := range plainGuidance(err) {
+			_, _ = fmt.Fprintln(w, styles.Redact(line))
+		}
+		if isUsageError(err) {
+			_, _ = fmt.Fprintln(w, "Try --help for usage.")
+		}
+		return
+	}
+
+	header := styles.ErrorHeader
+	if rich != nil && rich.Title != "" {
+		header = header.SetString(rich.Title)
+	}
+	_, _ = fmt.Fprintln(w, header.String())
+	_, _ = fmt.Fprintln(w, renderErrorTrace(styles, trace))
 	_, _ = fmt.Fprintln(w)
+	if rich != nil && rich.Details != "" {
+		_, _ = fmt.Fprintln(w, styles.ErrorDetails.Render(rich.Details))
+		_, _ = fmt.Fprintln(w)
+	}
+	if rich != nil && (rich.Hint != "" || rich.Command != "") {
+		if rich.Hint != "" {
+			_, _ = fmt.Fprintln(w, styles.ErrorHint.Render(rich.Hint))
+		}
+		if rich.Command != "" {
+			_, _ = fmt.Fprintln(w, strings.Repeat(" ", longPad)+styleCommand(rich.Command, styles.Program))
+		}
+		_, _ = fmt.Fprintln(w)
+	}
+	if url := docsURL(err); url != "" {
+		profile := colorprofile.NoTTY
+		if w, ok := w.(*colorprofile.Writer); ok {
+			profile = w.Profile
+		}
+		_, _ = fmt.Fprintln(w, strings.Repeat(" ", shortPad)+learnMore(styles, profile, url))
+		_, _ = fmt.Fprintln(w)
+	}
 	if isUsageError(err) {
 		_, _ = fmt.Fprintln(w, lipgloss.JoinHorizontal(
 			lipgloss.Left,
@@ -141,20 +254,28 @@ func isUsageError(err error) bool {
 		"unknown shorthand flag:",
 		"unknown command",
 		"invalid argument",
+		"bad flag syntax:",
+		"required flag(s) ",
+		"if any flags in the group ",
+		"at least one of the flags in the group ",
 	} {
 		if strings.HasPrefix(s, prefix) {
 			return true
 		}
 	}
-	return false
+	return argCountError.MatchString(s)
 }
 
+// argCountError matches the errors of the argument validators of cobra, e.g.
+// [cobra.ExactArgs].
+var argCountError = regexp.MustCompile(`^(accepts|requires) .*\barg\(s\), (only )?received \d+$`)
+
 func writeLongShort(w *colorprofile.Writer, styles Styles, longShort string) {
 	if longShort == "" {
 		return
 	}
 	_, _ = fmt.Fprintln(w)
-	_, _ = fmt.Fprintln(w, styles.Text.Width(width()).PaddingLeft(shortPad).Render(longShort))
+	_, _ = fmt.Fprintln(w, styles.Text.Width(styles.maxWidth()).PaddingLeft(shortPad).Render(longShort))
 }
 
 var otherArgsRe = regexp.MustCompile(`(\[.*\])`)
@@ -187,7 +308,9 @@ func styleUsage(c *cobra.Command, styles Program, complete bool) string {
 	useLine := []string{}
 	if complete {
 		parts := strings.Fields(u)
-		useLine = append(useLine, styles.Name.Render(parts[0]))
+		if len(parts) > 0 {
+			useLine = append(useLine, styles.Name.Render(parts[0]))
+		}
 		if len(parts) > 1 {
 			useLine = append(useLine, styles.Command.Render(" "+strings.Join(parts[1:], " ")))
 		}
@@ -228,139 +351,104 @@ func styleExamples(c *cobra.Command, styles Styles) []string {
 		return nil
 	}
 	usage := []string{}
-	examples := strings.Split(c.Example, "\n")
+	parser := newExampleParser(c)
 	var indent bool
-	for i, line := range examples {
-		line = strings.TrimSpace(line)
-		if (i == 0 || i == len(examples)-1) && line == "" {
-			continue
-		}
-		s := styleExample(c, line, indent, styles.Codeblock)
-		usage = append(usage, s)
-		indent = len(line) > 1 && (line[len(line)-1] == '\\' || line[len(line)-1] == '|')
+	for _, line := range exampleLines(c.Example) {
+		tokens := parser.parse(line)
+		usage = append(usage, styleExample(tokens, indent, styles.Codeblock))
+		indent = continues(tokens)
 	}
 
 	return usage
 }
 
-func styleExample(c *cobra.Command, line string, indent bool, styles Codeblock) string {
-	if strings.HasPrefix(line, "# ") {
-		return lipgloss.JoinHorizontal(
-			lipgloss.Left,
-			styles.Comment.Render(line),
-		)
+func styleExample(tokens []exampleToken, indent bool, styles Codeblock) string {
+	var parts []string
+	if indent {
+		parts = append(parts, styles.Program.DimmedArgument.Render("  "))
 	}
-
-	var isQuotedString bool
-	var foundProgramName bool
-	var isRedirecting bool
-	programName := c.Root().Name()
-	args := strings.Fields(line)
-	var cleanArgs []string
-	for i, arg := range args {
-		isQuoteStart := arg[0] == '"' || arg[0] == '\''
-		isQuoteEnd := arg[len(arg)-1] == '"' || arg[len(arg)-1] == '\''
-		isFlag := arg[0] == '-'
-
-		switch i {
-		case 0:
-			args[i] = ""
-			if indent {
-				args[i] = styles.Program.DimmedArgument.Render("  ")
-				indent = false
-			}
+	for _, token := range tokens {
+		var s string
+		switch token.kind {
+		case shellComment:
+			s = styles.Comment.Render(token.text)
+		case shellOperator:
+			s = styles.Program.Operator.Render(token.text)
+		case shellHeredoc:
+			s = styles.Program.QuotedString.Render(token.text)
+		case shellWord:
+			s = styleExampleWord(token, styles.Program)
 		default:
-			args[i] = styles.Program.DimmedArgument.Render(" ")
+			s = styles.Program.DimmedArgument.Render(token.text)
 		}
+		parts = append(parts, s)
+	}
+	return lipgloss.JoinHorizontal(lipgloss.Left, parts...)
+}
 
-		if isRedirecting {
-			args[i] += styles.Program.DimmedArgument.Render(arg)
-			isRedirecting = false
-			continue
+func styleExampleWord(token exampleToken, styles Program) string {
+	parts := token.parts
+	var prefix string
+	base := styles.Argument
+	switch token.role {
+	case roleProgram:
+		return styles.Name.Render(token.text)
+	case roleSubcommand:
+		return styles.Command.Render(token.text)
+	case roleRedirectTarget:
+		return styles.DimmedArgument.Render(token.text)
+	case roleAssignment:
+		n := assignment(token.shellToken)
+		prefix = styles.Flag.Render(parts[0].text[:n])
+		parts = append([]shellPart{{shellPlain, parts[0].text[n:]}}, parts[1:]...)
+	case roleFlag:
+		base = styles.Flag
+		if name, value, ok := strings.Cut(parts[0].text, "="); ok && parts[0].kind == shellPlain {
+			prefix = styles.Flag.Render(name + "=")
+			parts = append([]shellPart{{shellPlain, value}}, parts[1:]...)
+			base = styles.Argument
 		}
-
-		switch arg {
-		case "\\":
-			if i == len(args)-1 {
-				args[i] += styles.Program.DimmedArgument.Render(arg)
-				continue
-			}
-		case "|", "||", "-", "&", "&&":
-			args[i] += styles.Program.DimmedArgument.Render(arg)
-			continue
+	default:
+		if token.text == "-" {
+			return styles.DimmedArgument.Render(token.text)
 		}
+	}
 
-		if isRedirect(arg) {
-			args[i] += styles.Program.DimmedArgument.Render(arg)
-			isRedirecting = true
+	result := []string{prefix}
+	for _, part := range parts {
+		if part.text == "" {
 			continue
 		}
-
-		if !foundProgramName { //nolint:nestif
-			if isQuotedString {
-				args[i] += styles.Program.QuotedString.Render(arg)
-				isQuotedString = !isQuoteEnd
-				continue
-			}
-			if left, right, ok := strings.Cut(arg, "="); ok {
-				args[i] += styles.Program.Flag.Render(left + "=")
-				if right[0] == '"' {
-					isQuotedString = true
-					args[i] += styles.Program.QuotedString.Render(right)
-					continue
-				}
-				args[i] += styles.Program.Argument.Render(right)
-				continue
-			}
-
-			if arg == programName || slices.Contains(c.Root().Aliases, arg) {
-				args[i] += styles.Program.Name.Render(arg)
-				foundProgramName = true
-				continue
-			}
-		}
-
-		if !isQuoteStart && !isQuotedString && !isFlag {
-			cleanArgs = append(cleanArgs, arg)
+		switch part.kind {
+		case shellSingleQuoted, shellDoubleQuoted:
+			result = append(result, styles.QuotedString.Render(part.text))
+		case shellVariable, shellSubstitution:
+			result = append(result, styles.Variable.Render(part.text))
+		default:
+			result = append(result, base.Render(part.text))
 		}
+	}
+	return lipgloss.JoinHorizontal(lipgloss.Left, result...)
+}
 
-		if !isQuoteStart && !isFlag && isSubCommand(c, cleanArgs, arg) {
-			args[i] += styles.Program.Command.Render(arg)
+// continues reports whether the command in the given line goes on in the
+// next one.
+func continues(tokens []exampleToken) bool {
+	for i := len(tokens) - 1; i >= 0; i-- {
+		switch tokens[i].kind {
+		case shellSpace:
 			continue
-		}
-		isQuotedString = isQuotedString || isQuoteStart
-		if isQuotedString {
-			args[i] += styles.Program.QuotedString.Render(arg)
-			isQuotedString = !isQuoteEnd
-			continue
-		}
-		// handle a flag
-		if isFlag {
-			name, value, ok := strings.Cut(arg, "=")
-			// it is --flag=value
-			if ok {
-				args[i] += lipgloss.JoinHorizontal(
-					lipgloss.Left,
-					styles.Program.Flag.Render(name+"="),
-					styles.Program.Argument.Render(value),
-				)
-				continue
+		case shellContinuation:
+			return true
+		case shellOperator:
+			switch tokens[i].text {
+			case "|", "||", "&&", "|&":
+				return true
 			}
-			// it is either --bool-flag or --flag value
-			args[i] += lipgloss.JoinHorizontal(
-				lipgloss.Left,
-				styles.Program.Flag.Render(name),
-			)
-			continue
 		}
-
-		args[i] += styles.Program.Argument.Render(arg)
+		return false
 	}
-
-	return lipgloss.JoinHorizontal(
-		lipgloss.Left,
-		args...,
-	)
+	return false
 }
 
 func evalFlags(c *cobra.Command, styles Styles) (map[string]string, []string) {
@@ -402,15 +490,50 @@ func evalFlags(c *cobra.Command, styles Styles) (map[string]string, []string) {
 		}
 		help := strings.Join(helpLines, "\n")
 
-		if f.DefValue != "" && f.DefValue != "false" && f.DefValue != "0" && f.DefValue != "[]" {
+		switch {
+		case hasDefault(f) && isSensitive(f):
+			help += styles.FlagDefault.Render(" (set)")
+		case hasDefault(f):
 			help += styles.FlagDefault.Render(" (" + f.DefValue + ")")
 		}
+		if env := flagEnv(f); env != "" {
+			help += " " + styles.Program.Variable.Render("$"+env)
+		}
 		flags[key] = help
 		keys = append(keys, key)
 	})
 	return flags, keys
 }
 
+// evalEnv returns the environment variables the flags of the given command
+// are bound to, styled, and what they set, in the order of the flags.
+func evalEnv(c *cobra.Command, styles Styles) (map[string]string, []string) {
+	envs := map[string]string{}
+	keys := []string{}
+	c.Flags().VisitAll(func(f *pflag.Flag) {
+		env := flagEnv(f)
+		if f.Hidden || env == "" {
+			return
+		}
+		key := styles.Program.Variable.Render(env)
+		if _, ok := envs[key]; ok {
+			return
+		}
+		envs[key] = lipgloss.JoinHorizontal(
+			lipgloss.Left,
+			styles.FlagDescription.Render("Sets "),
+			styles.Program.Flag.Render("--"+f.Name),
+		)
+		keys = append(keys, key)
+	})
+	return envs, keys
+}
+
+// hasDefault reports whether the flag has a default value worth showing.
+func hasDefault(f *pflag.Flag) bool {
+	return f.DefValue != "" && f.DefValue != "false" && f.DefValue != "0" && f.DefValue != "[]"
+}
+
 // result is map[groupID]map[styled cmd name]styled cmd help, and the keys in
 // the order they are defined.
 func evalCmds(c *cobra.Command, styles Styles) (map[string](map[string]string), []string) {
@@ -426,6 +549,9 @@ func evalCmds(c *cobra.Command, styles Styles) (map[string](map[string]string),
 		}
 		key := padStyle.Render(styleUsage(sc, styles.Program, false))
 		help := styles.FlagDescription.Render(sc.Short)
+		if isDestructive(sc) {
+			help = lipgloss.JoinHorizontal(lipgloss.Left, help, " ", destructiveBadge(styles))
+		}
 		cmds[sc.GroupID][key] = help
 		keys = append(keys, key)
 	}
@@ -467,16 +593,5 @@ func calculateSpace(k1, k2 []string) int {
 
 func isSubCommand(c *cobra.Command, args []string, word string) bool {
 	cmd, _, _ := c.Root().Traverse(args)
-	return cmd != nil && cmd.Name() == word || slices.Contains(cmd.Aliases, word)
-}
-
-var redirectPrefixes = []string{">", "<", "&>", "2>", "1>", ">>", "2>>"}
-
-func isRedirect(s string) bool {
-	for _, p := range redirectPrefixes {
-		if strings.HasPrefix(s, p) {
-			return true
-		}
-	}
-	return false
+	return cmd != nil && (cmd.Name() == word || slices.Contains(cmd.Aliases, word))
 }
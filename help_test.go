@@ -0,0 +1,72 @@
+package fang
+
+import (
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func FuzzStyleUsage(f *testing.F) {
+	for _, use := range []string{
+		"",
+		" ",
+		"app",
+		"app [args]",
+		"[flags]",
+		"[command] [args]",
+		"app <name> [--flags] [something else]",
+		"app [",
+	} {
+		f.Add(use, "sub "+use)
+	}
+	f.Fuzz(func(t *testing.T, use, subUse string) {
+		root := &cobra.Command{Use: use}
+		sub := &cobra.Command{Use: subUse, Run: func(*cobra.Command, []string) {}}
+		root.AddCommand(sub)
+		root.Flags().Bool("flag", false, "")
+		for _, c := range []*cobra.Command{root, sub} {
+			_ = styleUsage(c, Program{}, true)
+			_ = styleUsage(c, Program{}, false)
+		}
+	})
+}
+
+func FuzzStyleExamples(f *testing.F) {
+	for _, example := range []string{
+		"",
+		"\n",
+		"app --flag=",
+		"FOO= app",
+		`app "unterminated`,
+		"app 'a' \\\n  sub",
+		"app <<EOF\nbody\nEOF",
+		"app $(echo ${FOO} `date`) 2>&1 | cat -; true && false || true # done",
+		"app $",
+		"app \\",
+		"app 2>",
+		"=",
+		"-",
+	} {
+		f.Add("app", example)
+	}
+	f.Fuzz(func(t *testing.T, use, example string) {
+		root := &cobra.Command{Use: use, Example: example}
+		root.AddCommand(&cobra.Command{Use: "sub", Aliases: []string{"s"}})
+		_ = styleExamples(root, Styles{})
+	})
+}
+
+func FuzzEvalFlags(f *testing.F) {
+	f.Add("name", "n", "jane", "the name")
+	f.Add("", "", "", "")
+	f.Add("multi", "", "", "line one\nline two\n\n")
+	f.Fuzz(func(t *testing.T, name, shorthand, value, usage string) {
+		if len(shorthand) > 1 {
+			shorthand = shorthand[:1]
+		}
+		c := &cobra.Command{Use: "app"}
+		c.Flags().StringP(name, shorthand, value, usage)
+		_, _ = evalFlags(c, Styles{})
+		_, _ = evalCmds(c, Styles{})
+	})
+}
@@ -0,0 +1,50 @@
+package fang
+
+import "github.com/spf13/cobra"
+
+// beforeRun makes the runnable commands of the given tree call fn once their
+// flags are parsed, before their hooks run and their required flags are
+// checked, i.e. in the first of their PersistentPreRun and PreRun hooks to
+// run.
+//
+// fn is called with the command being executed, and should be idempotent, as
+// it may run more than once with [cobra.EnableTraverseRunHooks].
+func beforeRun(root *cobra.Command, fn func(*cobra.Command, []string) error) {
+	wrapped := map[*cobra.Command]bool{}
+	walk(root, func(c *cobra.Command) {
+		if !c.Runnable() {
+			return
+		}
+		for p := c; p != nil; p = p.Parent() {
+			if p.PersistentPreRunE == nil && p.PersistentPreRun == nil {
+				continue
+			}
+			if !wrapped[p] {
+				wrapped[p] = true
+				p.PersistentPreRunE, p.PersistentPreRun = wrapHook(fn, p.PersistentPreRunE, p.PersistentPreRun), nil
+			}
+			return
+		}
+		c.PreRunE, c.PreRun = wrapHook(fn, c.PreRunE, c.PreRun), nil
+	})
+}
+
+// wrapHook returns a hook calling fn, then hookE or hook, whichever is set.
+func wrapHook(
+	fn func(*cobra.Command, []string) error,
+	hookE func(*cobra.Command, []string) error,
+	hook func(*cobra.Command, []string),
+) func(*cobra.Command, []string) error {
+	return func(c *cobra.Command, args []string) error {
+		if err := fn(c, args); err != nil {
+			return err
+		}
+		if hookE != nil {
+			return hookE(c, args)
+		}
+		if hook != nil {
+			hook(c, args)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,140 @@
+package fang
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+
+	"github.com/spf13/cobra"
+)
+
+// ErrorKind is the kind of an error, as reported by [WithJSONErrors].
+type ErrorKind string
+
+// Error kinds.
+const (
+	UsageError    ErrorKind = "usage"    // the command was used incorrectly.
+	RuntimeError  ErrorKind = "runtime"  // the command failed.
+	CanceledError ErrorKind = "canceled" // the command was interrupted.
+)
+
+// ExitCoder is implemented by errors that set the exit code of the program,
+// see [ExitCode].
+type ExitCoder interface {
+	ExitCode() int
+}
+
+// Kind returns the kind of the given error.
+func Kind(err error) ErrorKind {
+	switch {
+	case errors.Is(err, context.Canceled):
+		return CanceledError
+	case isUsageError(err):
+		return UsageError
+	default:
+		return RuntimeError
+	}
+}
+
+// ExitCode returns the exit code the program should exit with after the given
+// error, which is 0 if it is nil, the one of the first [ExitCoder] in its
+// chain if any, and otherwise 2 for usage errors, 130 if the command was
+// canceled, e.g. with [WithNotifySignal], and 1 for everything else.
+//
+// Example:
+//
+//	if err := fang.Execute(ctx, cmd); err != nil {
+//		os.Exit(fang.ExitCode(err))
+//	}
+func ExitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	var coder ExitCoder
+	if errors.As(err, &coder) {
+		return coder.ExitCode()
+	}
+	switch Kind(err) {
+	case UsageError:
+		return 2 //nolint:mnd
+	case CanceledError:
+		return 130 //nolint:mnd
+	default:
+		return 1
+	}
+}
+
+// errorFormatEnv is the environment variable that, set to "json", enables
+// [WithJSONErrors].
+const errorFormatEnv = "FANG_ERROR_FORMAT"
+
+type jsonError struct {
+	Message  string      `json:"message"`
+	Kind     ErrorKind   `json:"kind"`
+	ExitCode int         `json:"exit_code"`
+	Command  string      `json:"command"`
+	Title    string      `json:"title,omitempty"`
+	Details  string      `json:"details,omitempty"`
+	Hint     string      `json:"hint,omitempty"`
+	Run      string      `json:"run,omitempty"`
+	DocsURL  string      `json:"docs_url,omitempty"`
+	Causes   []jsonCause `json:"causes,omitempty"`
+}
+
+type jsonCause struct {
+	Message string      `json:"message"`
+	Causes  []jsonCause `json:"causes,omitempty"`
+}
+
+// writeJSONError writes the given error, returned by the given command, as a
+// single line JSON object.
+func writeJSONError(w io.Writer, c *cobra.Command, err error, redact func(string) string) {
+	out := jsonError{
+		Message:  redact(err.Error()),
+		Kind:     Kind(err),
+		ExitCode: ExitCode(err),
+		Command:  c.CommandPath(),
+		DocsURL:  docsURL(err),
+		Causes:   jsonCauses(err, redact),
+	}
+	var rich *Error
+	if errors.As(err, &rich) {
+		out.Title = redact(rich.Title)
+		out.Details = redact(rich.Details)
+		out.Hint = redact(rich.Hint)
+		out.Run = redact(rich.Command)
+	}
+	if out.Hint == "" && out.Kind == UsageError {
+		out.Hint = "Try --help for usage."
+	}
+	_ = json.NewEncoder(w).Encode(out)
+}
+
+// jsonCauses returns the errors wrapped by the given one, leaving out the
+// ones with the same message as the error wrapping them, e.g. an [Error].
+func jsonCauses(err error, redact func(string) string) []jsonCause {
+	var wrapped []error
+	switch u := err.(type) {
+	case interface{ Unwrap() error }:
+		wrapped = []error{u.Unwrap()}
+	case interface{ Unwrap() []error }:
+		wrapped = u.Unwrap()
+	}
+
+	var causes []jsonCause
+	for _, cause := range wrapped {
+		if cause == nil {
+			continue
+		}
+		if cause.Error() == err.Error() {
+			causes = append(causes, jsonCauses(cause, redact)...)
+			continue
+		}
+		causes = append(causes, jsonCause{
+			Message: redact(cause.Error()),
+			Causes:  jsonCauses(cause, redact),
+		})
+	}
+	return causes
+}
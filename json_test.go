@@ -0,0 +1,34 @@
+package fang
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type exitError int
+
+func (e exitError) Error() string { return fmt.Sprintf("exit %d", int(e)) }
+func (e exitError) ExitCode() int { return int(e) }
+
+func TestExitCode(t *testing.T) {
+	for name, tt := range map[string]struct {
+		err  error
+		want int
+	}{
+		"nil":      {nil, 0},
+		"runtime":  {errors.New("boom"), 1},
+		"usage":    {errors.New("unknown flag: --nope"), 2},
+		"args":     {errors.New("requires at least 1 arg(s), only received 0"), 2},
+		"required": {errors.New(`required flag(s) "output" not set`), 2},
+		"canceled": {fmt.Errorf("could not wait: %w", context.Canceled), 130},
+		"coder":    {fmt.Errorf("could not run: %w", exitError(3)), 3},
+	} {
+		t.Run(name, func(t *testing.T) {
+			require.Equal(t, tt.want, ExitCode(tt.err))
+		})
+	}
+}
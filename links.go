@@ -0,0 +1,95 @@
+package fang
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/colorprofile"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// DocsURLAnnotation is the command annotation holding the URL of the
+// documentation of the command, which is linked to at the end of its help.
+//
+// Example:
+//
+//	cmd.Annotations = map[string]string{
+//		fang.DocsURLAnnotation: "https://example.com/docs/deploy",
+//	}
+const DocsURLAnnotation = "fang_docs_url"
+
+// DocsLinker is implemented by errors that link to their documentation, e.g.
+// a runbook. [DefaultErrorHandler] links to it after the error message.
+type DocsLinker interface {
+	DocsURL() string
+}
+
+// docsURL returns the URL of the documentation of the first error in the
+// chain linking to one, if any. Errors with an empty URL, e.g. an [Error]
+// without one, don't stop the search.
+func docsURL(err error) string {
+	if linker, ok := err.(DocsLinker); ok && linker.DocsURL() != "" {
+		return linker.DocsURL()
+	}
+	switch err := err.(type) {
+	case interface{ Unwrap() error }:
+		return docsURL(err.Unwrap())
+	case interface{ Unwrap() []error }:
+		for _, err := range err.Unwrap() {
+			if url := docsURL(err); url != "" {
+				return url
+			}
+		}
+	}
+	return ""
+}
+
+// learnMore returns a "Learn more" link to the given URL, as an OSC 8
+// hyperlink if the terminal supports them, or followed by the plain URL
+// otherwise.
+func learnMore(styles Styles, profile colorprofile.Profile, url string) string {
+	if styles.hyperlinks && profile > colorprofile.NoTTY {
+		return ansi.SetHyperlink(url) + styles.Link.Render("Learn more") + ansi.ResetHyperlink()
+	}
+	return styles.Text.Render("Learn more: ") + styles.Link.Render(url)
+}
+
+// supportsHyperlinks reports whether the terminal in the given environment
+// is known to support OSC 8 hyperlinks. FORCE_HYPERLINK can be set to a
+// boolean to override it.
+func supportsHyperlinks(environ []string) bool {
+	getenv := func(key string) string {
+		for _, kv := range environ {
+			if k, v, ok := strings.Cut(kv, "="); ok && k == key {
+				return v
+			}
+		}
+		return ""
+	}
+
+	if v, err := strconv.ParseBool(getenv("FORCE_HYPERLINK")); err == nil {
+		return v
+	}
+	if getenv("CI") != "" {
+		return false
+	}
+	switch getenv("TERM_PROGRAM") {
+	case "iTerm.app", "WezTerm", "vscode", "ghostty", "Hyper", "Tabby", "rio", "WarpTerminal":
+		return true
+	}
+	for _, key := range []string{"WT_SESSION", "KITTY_WINDOW_ID", "KONSOLE_VERSION", "DOMTERM"} {
+		if getenv(key) != "" {
+			return true
+		}
+	}
+	if v, err := strconv.Atoi(getenv("VTE_VERSION")); err == nil && v >= 5000 {
+		return true
+	}
+	term := getenv("TERM")
+	for _, name := range []string{"kitty", "alacritty", "foot", "ghostty", "wezterm"} {
+		if strings.Contains(term, name) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,68 @@
+package fang
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/charmbracelet/colorprofile"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSupportsHyperlinks(t *testing.T) {
+	for name, tt := range map[string]struct {
+		environ []string
+		want    bool
+	}{
+		"empty":            {nil, false},
+		"iterm":            {[]string{"TERM_PROGRAM=iTerm.app"}, true},
+		"kitty":            {[]string{"TERM=xterm-kitty"}, true},
+		"old vte":          {[]string{"VTE_VERSION=4800"}, false},
+		"vte":              {[]string{"VTE_VERSION=6003"}, true},
+		"ci":               {[]string{"CI=true", "TERM_PROGRAM=vscode"}, false},
+		"forced":           {[]string{"CI=true", "FORCE_HYPERLINK=1"}, true},
+		"forced off":       {[]string{"FORCE_HYPERLINK=0", "WT_SESSION=abc"}, false},
+		"unknown xterm":    {[]string{"TERM=xterm-256color"}, false},
+		"windows terminal": {[]string{"WT_SESSION=abc"}, true},
+	} {
+		t.Run(name, func(t *testing.T) {
+			require.Equal(t, tt.want, supportsHyperlinks(tt.environ))
+		})
+	}
+}
+
+func TestLearnMore(t *testing.T) {
+	const url = "https://example.com/docs"
+	styles := Styles{hyperlinks: true}
+	require.Equal(t, "\x1b]8;;"+url+"\x07Learn more\x1b]8;;\x07", learnMore(styles, colorprofile.TrueColor, url))
+	require.Equal(t, "Learn more: "+url, learnMore(styles, colorprofile.NoTTY, url))
+
+	styles.hyperlinks = false
+	require.Equal(t, "Learn more: "+url, learnMore(styles, colorprofile.TrueColor, url))
+}
+
+type runbookError struct{}
+
+func (runbookError) Error() string   { return "the cluster is unreachable" }
+func (runbookError) DocsURL() string { return "https://example.com/runbooks/unreachable" }
+
+func TestDocsURL(t *testing.T) {
+	const runbook = "https://example.com/runbooks/unreachable"
+	for name, tt := range map[string]struct {
+		err  error
+		want string
+	}{
+		"nil":                 {nil, ""},
+		"plain":               {errors.New("nope"), ""},
+		"linker":              {runbookError{}, runbook},
+		"wrapped":             {fmt.Errorf("could not deploy: %w", runbookError{}), runbook},
+		"joined":              {errors.Join(errors.New("nope"), runbookError{}), runbook},
+		"rich error":          {&Error{Err: errors.New("nope"), URL: "https://example.com"}, "https://example.com"},
+		"rich error wrapping": {&Error{Err: runbookError{}, Hint: "retry"}, runbook},
+		"rich error first":    {&Error{Err: runbookError{}, URL: "https://example.com"}, "https://example.com"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			require.Equal(t, tt.want, docsURL(tt.err))
+		})
+	}
+}
@@ -0,0 +1,457 @@
+package fang
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// LintIssue is a problem found in the definition of a command.
+type LintIssue struct {
+	Command string // the path of the command, e.g. "app sub".
+	Message string
+}
+
+// String implements [fmt.Stringer].
+func (i LintIssue) String() string {
+	return i.Command + ": " + i.Message
+}
+
+// LintExamples checks the examples of every command in the tree, parsing them
+// the same way they are highlighted in the help, and reports unknown
+// subcommands, unknown flags, missing required flags and wrong argument
+// counts.
+//
+// Example:
+//
+//	func TestExamples(t *testing.T) {
+//		for _, issue := range fang.LintExamples(newRootCmd()) {
+//			t.Error(issue)
+//		}
+//	}
+func LintExamples(root *cobra.Command) []LintIssue {
+	var issues []LintIssue
+	walk(root, func(c *cobra.Command) {
+		for _, invocation := range parseExamples(c) {
+			for _, msg := range lintInvocation(root, invocation) {
+				issues = append(issues, LintIssue{
+					Command: c.CommandPath(),
+					Message: fmt.Sprintf("example %q: %s", invocationString(invocation), msg),
+				})
+			}
+		}
+	})
+	return issues
+}
+
+// Lint checks the definition of every visible command in the tree for
+// problems that show up in the help: missing or period-terminated short
+// descriptions, inconsistent capitalization, flags without usage, shorthands
+// colliding with the persistent flags of a parent, empty or undefined groups
+// and examples running hidden commands.
+//
+// Fang capitalizes the first word of descriptions when rendering the help,
+// which hides inconsistent capitalization there, but not in the manpages nor
+// in the shell completions.
+//
+// See also [LintExamples].
+func Lint(root *cobra.Command) []LintIssue {
+	var issues []LintIssue
+	upper := mostlyUpper(root)
+	walk(root, func(c *cobra.Command) {
+		if isHidden(c) {
+			return
+		}
+		for _, msg := range lintCommand(c, upper) {
+			issues = append(issues, LintIssue{
+				Command: c.CommandPath(),
+				Message: msg,
+			})
+		}
+	})
+	return issues
+}
+
+func lintCommand(c *cobra.Command, upper bool) []string {
+	var issues []string
+	switch short := strings.TrimSpace(c.Short); {
+	case short == "":
+		issues = append(issues, "missing short description")
+	case strings.HasSuffix(short, "."):
+		issues = append(issues, "short description ends with a period")
+	}
+	if msg := lintCase(c.Short, upper); msg != "" {
+		issues = append(issues, "short description "+msg)
+	}
+
+	visitOwnFlags(c, func(f *pflag.Flag) {
+		if f.Hidden || isDefaultFlag(f.Name) {
+			return
+		}
+		if strings.TrimSpace(f.Usage) == "" {
+			issues = append(issues, fmt.Sprintf("flag --%s has no usage", f.Name))
+		}
+		if msg := lintCase(f.Usage, upper); msg != "" {
+			issues = append(issues, fmt.Sprintf("usage of flag --%s %s", f.Name, msg))
+		}
+		if f.Shorthand == "" {
+			return
+		}
+		for p := c.Parent(); p != nil; p = p.Parent() {
+			pf := p.PersistentFlags().ShorthandLookup(f.Shorthand)
+			if pf != nil && pf != f {
+				issues = append(issues, fmt.Sprintf(
+					"shorthand -%s of flag --%s collides with persistent flag --%s of %q",
+					f.Shorthand, f.Name, pf.Name, p.CommandPath(),
+				))
+				return
+			}
+		}
+	})
+
+	for _, g := range c.Groups() {
+		if !slices.ContainsFunc(c.Commands(), func(sc *cobra.Command) bool {
+			return sc.GroupID == g.ID && sc.IsAvailableCommand()
+		}) {
+			issues = append(issues, fmt.Sprintf("group %q has no commands", g.ID))
+		}
+	}
+	if c.GroupID != "" && c.HasParent() && !c.Parent().ContainsGroup(c.GroupID) {
+		issues = append(issues, fmt.Sprintf("group %q is not defined in %q", c.GroupID, c.Parent().CommandPath()))
+	}
+
+	for _, invocation := range parseExamples(c) {
+		if hidden := hiddenCommand(c.Root(), invocation); hidden != nil {
+			issues = append(issues, fmt.Sprintf(
+				"example %q: runs hidden command %q",
+				invocationString(invocation), hidden.CommandPath(),
+			))
+		}
+	}
+	return issues
+}
+
+// mostlyUpper reports whether most descriptions in the tree start with an
+// uppercase letter, which is what we compare each one against.
+func mostlyUpper(root *cobra.Command) bool {
+	var upper, lower int
+	count := func(s string) {
+		switch r := firstRune(s); {
+		case unicode.IsUpper(r):
+			upper++
+		case unicode.IsLower(r):
+			lower++
+		}
+	}
+	walk(root, func(c *cobra.Command) {
+		if isHidden(c) {
+			return
+		}
+		count(c.Short)
+		visitOwnFlags(c, func(f *pflag.Flag) {
+			if !f.Hidden && !isDefaultFlag(f.Name) {
+				count(f.Usage)
+			}
+		})
+	})
+	return upper >= lower
+}
+
+// lintCase returns a message if the given description does not start with
+// the expected case.
+func lintCase(s string, upper bool) string {
+	switch r := firstRune(s); {
+	case upper && unicode.IsLower(r):
+		return "starts with a lowercase letter, unlike most descriptions"
+	case !upper && unicode.IsUpper(r):
+		return "starts with an uppercase letter, unlike most descriptions"
+	}
+	return ""
+}
+
+func firstRune(s string) rune {
+	r, _ := utf8.DecodeRuneInString(strings.TrimSpace(s))
+	return r
+}
+
+// hiddenCommand returns the first hidden command the given invocation runs,
+// if any.
+func hiddenCommand(root *cobra.Command, invocation []exampleToken) *cobra.Command {
+	cmd := root
+	for _, word := range invocation[1:] {
+		if word.role != roleSubcommand {
+			continue
+		}
+		sc := findSubCommand(cmd, word.value())
+		if sc == nil {
+			return nil
+		}
+		if sc.Hidden {
+			return sc
+		}
+		cmd = sc
+	}
+	return nil
+}
+
+// isHidden reports whether the given command or any of its parents is
+// hidden.
+func isHidden(c *cobra.Command) bool {
+	for ; c != nil; c = c.Parent() {
+		if c.Hidden {
+			return true
+		}
+	}
+	return false
+}
+
+// walk calls fn for the given command and all its subcommands.
+func walk(c *cobra.Command, fn func(*cobra.Command)) {
+	fn(c)
+	for _, sc := range c.Commands() {
+		walk(sc, fn)
+	}
+}
+
+// parseExamples returns the invocations of our program in the examples of
+// the given command.
+func parseExamples(c *cobra.Command) [][]exampleToken {
+	parser := newExampleParser(c)
+	for _, line := range exampleLines(c.Example) {
+		_ = parser.parse(line)
+	}
+	parser.endCommand()
+	return parser.invocations
+}
+
+func invocationString(invocation []exampleToken) string {
+	words := make([]string, 0, len(invocation))
+	for _, w := range invocation {
+		words = append(words, w.text)
+	}
+	return strings.Join(words, " ")
+}
+
+// lintInvocation checks an invocation of our program, starting with the
+// program name, against the command tree.
+func lintInvocation(root *cobra.Command, invocation []exampleToken) []string {
+	var issues []string
+	cmd := root
+	var positional []string
+	seen := map[string]bool{}
+	var expands, help bool
+
+	// takeValue returns whether the flag needs a value that is in the next
+	// word.
+	takeValue := func(f *pflag.Flag, i int, name string) bool {
+		if f.NoOptDefVal != "" {
+			return false
+		}
+		if i+1 >= len(invocation) {
+			issues = append(issues, "flag needs an argument: "+name)
+			return false
+		}
+		return true
+	}
+
+	for i := 1; i < len(invocation); i++ {
+		word := invocation[i]
+		value := word.value()
+		switch {
+		case word.role == roleSubcommand && len(positional) == 0:
+			if sc := findSubCommand(cmd, value); sc != nil {
+				cmd = sc
+				continue
+			}
+		case word.role == roleFlag && value == "--":
+			for _, w := range invocation[i+1:] {
+				positional = append(positional, w.value())
+			}
+			i = len(invocation)
+			continue
+		case word.role == roleFlag && strings.HasPrefix(value, "--"):
+			name, _, hasValue := strings.Cut(value[2:], "=")
+			help = help || name == "help"
+			f := lookupFlag(cmd, name, "")
+			if f == nil {
+				if !isDefaultFlag(name) {
+					issues = append(issues, "unknown flag: --"+name)
+				}
+				continue
+			}
+			seen[f.Name] = true
+			if !hasValue && takeValue(f, i, "--"+name) {
+				i++
+			}
+			continue
+		case word.role == roleFlag:
+			shorthands := value[1:]
+			for j := 0; j < len(shorthands); j++ {
+				s := shorthands[j : j+1]
+				help = help || s == "h"
+				f := lookupFlag(cmd, "", s)
+				if f == nil {
+					if !isDefaultFlag(s) {
+						issues = append(issues, "unknown shorthand flag: -"+s)
+					}
+					continue
+				}
+				seen[f.Name] = true
+				if f.NoOptDefVal != "" {
+					continue
+				}
+				// the rest of the word is the value, e.g. -nfoo or -n=foo.
+				if j+1 < len(shorthands) {
+					break
+				}
+				if takeValue(f, i, "-"+s) {
+					i++
+				}
+			}
+			continue
+		}
+		positional = append(positional, value)
+		expands = expands || isExpansion(word.shellToken)
+	}
+
+	if help {
+		return issues
+	}
+
+	if len(positional) > 0 && cmd.HasAvailableSubCommands() &&
+		(!cmd.Runnable() || cmd.Args == nil && !cmd.HasParent()) {
+		return append(issues, fmt.Sprintf("unknown command %q for %q", positional[0], cmd.CommandPath()))
+	}
+
+	visitAllFlags(cmd, func(f *pflag.Flag) {
+		if isRequired(f) && !seen[f.Name] {
+			issues = append(issues, "missing required flag: --"+f.Name)
+		}
+	})
+
+	// expansions may turn into any number of arguments.
+	if !expands {
+		if err := cmd.ValidateArgs(positional); err != nil {
+			issues = append(issues, err.Error())
+		}
+	}
+	return issues
+}
+
+// findSubCommand returns the available subcommand with the given name or
+// alias.
+func findSubCommand(c *cobra.Command, name string) *cobra.Command {
+	for _, sc := range c.Commands() {
+		if sc.Name() == name || slices.Contains(sc.Aliases, name) {
+			return sc
+		}
+	}
+	return nil
+}
+
+// flagSets returns the flag sets of the given command, then the persistent
+// ones of its parents, from the closest.
+//
+// The flags of the parents are not merged with [cobra.Command.InheritedFlags],
+// as it panics on the shorthand collisions [Lint] reports.
+func flagSets(c *cobra.Command) []*pflag.FlagSet {
+	sets := []*pflag.FlagSet{c.Flags(), c.PersistentFlags()}
+	for p := c.Parent(); p != nil; p = p.Parent() {
+		sets = append(sets, p.PersistentFlags())
+	}
+	return sets
+}
+
+// lookupFlag looks up a flag of the given command by name or shorthand,
+// including the flags inherited from its parents.
+func lookupFlag(c *cobra.Command, name, shorthand string) *pflag.Flag {
+	for _, fs := range flagSets(c) {
+		var f *pflag.Flag
+		if name != "" {
+			f = fs.Lookup(name)
+		} else {
+			f = fs.ShorthandLookup(shorthand)
+		}
+		if f != nil {
+			return f
+		}
+	}
+	return nil
+}
+
+// visitAllFlags calls fn for each flag of the given command, including the
+// flags inherited from its parents.
+func visitAllFlags(c *cobra.Command, fn func(*pflag.Flag)) {
+	seen := map[string]bool{}
+	for _, fs := range flagSets(c) {
+		fs.VisitAll(func(f *pflag.Flag) {
+			if seen[f.Name] {
+				return
+			}
+			seen[f.Name] = true
+			fn(f)
+		})
+	}
+}
+
+// visitOwnFlags calls fn for each flag defined by the given command, local or
+// persistent, but not the ones inherited from its parents.
+func visitOwnFlags(c *cobra.Command, fn func(*pflag.Flag)) {
+	inherited := func(f *pflag.Flag) bool {
+		for p := c.Parent(); p != nil; p = p.Parent() {
+			if p.PersistentFlags().Lookup(f.Name) == f {
+				return true
+			}
+		}
+		return false
+	}
+	seen := map[string]bool{}
+	for _, fs := range []*pflag.FlagSet{c.PersistentFlags(), c.Flags()} {
+		fs.VisitAll(func(f *pflag.Flag) {
+			if seen[f.Name] || inherited(f) {
+				return
+			}
+			seen[f.Name] = true
+			fn(f)
+		})
+	}
+}
+
+// isDefaultCommand reports whether the command is one of the commands cobra
+// adds, help and completion.
+func isDefaultCommand(c *cobra.Command) bool {
+	return c.HasParent() && !c.Parent().HasParent() && (c.Name() == "help" || c.Name() == "completion")
+}
+
+// isDefaultFlag reports whether the flag is one of the flags cobra adds when
+// the program is executed.
+func isDefaultFlag(name string) bool {
+	switch name {
+	case "help", "h", "version", "v":
+		return true
+	}
+	return false
+}
+
+func isRequired(f *pflag.Flag) bool {
+	return slices.Contains(f.Annotations[cobra.BashCompOneRequiredFlag], "true")
+}
+
+// isExpansion reports whether the word has unquoted expansions, which may
+// expand to any number of words.
+func isExpansion(word shellToken) bool {
+	if word.quoted() {
+		return false
+	}
+	for _, p := range word.parts {
+		if p.kind == shellVariable || p.kind == shellSubstitution {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,137 @@
+package fang_test
+
+import (
+	"testing"
+
+	"charm.land/fang/v2"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLintExamples(t *testing.T) {
+	root := &cobra.Command{
+		Use: "app",
+		Example: `
+# Valid:
+app --verbose get foo --output json
+app g -vo=json foo | cat -
+FOO=bar app --config=$HOME/.app.toml get "foo bar" # comment
+app get $(cat ids.txt)
+app --help
+app get -h
+
+# Invalid:
+app nope
+app --nope get foo
+app get -x foo
+app get
+app get foo bar
+app delete
+app get foo --output
+		`,
+	}
+	root.PersistentFlags().BoolP("verbose", "v", false, "verbose output")
+	root.PersistentFlags().String("config", "", "config file")
+	get := &cobra.Command{
+		Use:     "get <id>",
+		Aliases: []string{"g"},
+		Args:    cobra.ExactArgs(1),
+		Run:     func(*cobra.Command, []string) {},
+	}
+	get.Flags().StringP("output", "o", "text", "output format")
+	del := &cobra.Command{
+		Use:     "delete",
+		Example: `app delete --id 10 --force`,
+		Run:     func(*cobra.Command, []string) {},
+	}
+	del.Flags().String("id", "", "the id")
+	_ = del.MarkFlagRequired("id")
+	root.AddCommand(get, del)
+
+	var issues []string
+	for _, issue := range fang.LintExamples(root) {
+		issues = append(issues, issue.String())
+	}
+	require.Equal(t, []string{
+		`app: example "app nope": unknown command "nope" for "app"`,
+		`app: example "app --nope get foo": unknown flag: --nope`,
+		`app: example "app get -x foo": unknown shorthand flag: -x`,
+		`app: example "app get": accepts 1 arg(s), received 0`,
+		`app: example "app get foo bar": accepts 1 arg(s), received 2`,
+		`app: example "app delete": missing required flag: --id`,
+		`app: example "app get foo --output": flag needs an argument: --output`,
+		`app delete: example "app delete --id 10 --force": unknown flag: --force`,
+	}, issues)
+}
+
+func TestLintExamplesCollidingShorthand(t *testing.T) {
+	root := &cobra.Command{Use: "app", Short: "An app", Example: "app get foo -c 2 --verbose"}
+	root.PersistentFlags().BoolP("verbose", "v", false, "Verbose output")
+	root.PersistentFlags().StringP("config", "c", "", "Config file")
+	get := &cobra.Command{
+		Use:   "get <id>",
+		Short: "Get something",
+		Args:  cobra.ExactArgs(1),
+		Run:   func(*cobra.Command, []string) {},
+	}
+	get.Flags().StringP("count", "c", "", "Number of items")
+	root.AddCommand(get)
+
+	require.Empty(t, fang.LintExamples(root))
+	require.Len(t, fang.Lint(root), 1)
+}
+
+func TestLint(t *testing.T) {
+	root := &cobra.Command{
+		Use:   "app",
+		Short: "An app",
+		Example: `
+app get foo
+app debug dump
+		`,
+	}
+	root.PersistentFlags().BoolP("verbose", "v", false, "Verbose output")
+	root.PersistentFlags().StringP("config", "c", "", "Config file")
+	root.AddGroup(
+		&cobra.Group{ID: "main", Title: "Main"},
+		&cobra.Group{ID: "empty", Title: "Empty"},
+	)
+	get := &cobra.Command{
+		Use:     "get <id>",
+		Short:   "Get something.",
+		GroupID: "main",
+		Run:     func(*cobra.Command, []string) {},
+	}
+	get.Flags().StringP("output", "o", "text", "output format")
+	get.Flags().StringP("count", "c", "", "")
+	del := &cobra.Command{
+		Use:     "delete",
+		GroupID: "other",
+		Run:     func(*cobra.Command, []string) {},
+	}
+	debug := &cobra.Command{
+		Use:    "debug",
+		Short:  "hidden, so not linted.",
+		Hidden: true,
+	}
+	debug.AddCommand(&cobra.Command{
+		Use: "dump",
+		Run: func(*cobra.Command, []string) {},
+	})
+	root.AddCommand(get, del, debug)
+
+	var issues []string
+	for _, issue := range fang.Lint(root) {
+		issues = append(issues, issue.String())
+	}
+	require.Equal(t, []string{
+		`app: group "empty" has no commands`,
+		`app: example "app debug dump": runs hidden command "app debug"`,
+		`app delete: missing short description`,
+		`app delete: group "other" is not defined in "app"`,
+		`app get: short description ends with a period`,
+		`app get: flag --count has no usage`,
+		`app get: shorthand -c of flag --count collides with persistent flag --config of "app"`,
+		`app get: usage of flag --output starts with a lowercase letter, unlike most descriptions`,
+	}, issues)
+}
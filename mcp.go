@@ -0,0 +1,526 @@
+package fang
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// MCPAnnotation is the command annotation controlling whether the `mcp`
+// command exposes a command, and its subcommands, as tools. Its value is
+// either [MCPAllow] or [MCPDeny].
+//
+// Commands are exposed by default, unless hidden or destructive, see
+// [DestructiveAnnotation]. Destructive commands which are allowed explicitly
+// are marked as such, leaving the confirmation to the MCP client, and run as
+// if --yes was set.
+//
+// Example:
+//
+//	cmd.Annotations = map[string]string{fang.MCPAnnotation: fang.MCPDeny}
+const MCPAnnotation = "fang_mcp"
+
+// Values of [MCPAnnotation].
+const (
+	MCPAllow = "allow"
+	MCPDeny  = "deny"
+)
+
+const mcpProtocolVersion = "2025-06-18"
+
+// JSON-RPC error codes.
+const (
+	rpcParseError     = -32700
+	rpcMethodNotFound = -32601
+	rpcInvalidParams  = -32602
+)
+
+var mcpProtocolVersions = []string{"2024-11-05", "2025-03-26", mcpProtocolVersion}
+
+type rpcRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+type rpcResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  any             `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+type mcpTool struct {
+	Name        string              `json:"name"`
+	Description string              `json:"description,omitempty"`
+	InputSchema map[string]any      `json:"inputSchema"`
+	Annotations *mcpToolAnnotations `json:"annotations,omitempty"`
+
+	cmd *cobra.Command
+}
+
+type mcpToolAnnotations struct {
+	DestructiveHint bool `json:"destructiveHint"`
+}
+
+type mcpContent struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+type mcpCallResult struct {
+	Content []mcpContent `json:"content"`
+	IsError bool         `json:"isError,omitempty"`
+}
+
+func mcpCmd(opts settings) *cobra.Command {
+	return &cobra.Command{
+		Use:                   "mcp",
+		Short:                 "Serve the commands as MCP tools over stdio",
+		SilenceUsage:          true,
+		DisableFlagsInUseLine: true,
+		Hidden:                true,
+		Args:                  cobra.NoArgs,
+		Annotations:           map[string]string{MCPAnnotation: MCPDeny},
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			s := &mcpServer{
+				root:           cmd.Root(),
+				in:             cmd.InOrStdin(),
+				out:            cmd.OutOrStdout(),
+				err:            cmd.ErrOrStderr(),
+				tools:          mcpTools(cmd.Root()),
+				redactPatterns: opts.redactPatterns,
+			}
+			return s.serve(cmd.Context())
+		},
+	}
+}
+
+type mcpServer struct {
+	root           *cobra.Command
+	in             io.Reader
+	out            io.Writer
+	err            io.Writer
+	tools          []mcpTool
+	redactPatterns []*regexp.Regexp
+}
+
+// serve reads JSON-RPC messages, one per line, until the input is closed.
+func (s *mcpServer) serve(ctx context.Context) error {
+	r := bufio.NewReader(s.in)
+	enc := json.NewEncoder(s.out)
+	for {
+		line, err := r.ReadBytes('\n')
+		if len(bytes.TrimSpace(line)) > 0 {
+			if res := s.handle(ctx, line); res != nil {
+				if err := enc.Encode(res); err != nil {
+					return fmt.Errorf("could not write response: %w", err)
+				}
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("could not read request: %w", err)
+		}
+	}
+}
+
+// handle handles a single message, returning the response, or nil for
+// notifications.
+func (s *mcpServer) handle(ctx context.Context, msg []byte) *rpcResponse {
+	var req rpcRequest
+	if err := json.Unmarshal(msg, &req); err != nil {
+		return &rpcResponse{
+			JSONRPC: "2.0",
+			ID:      json.RawMessage("null"),
+			Error:   &rpcError{rpcParseError, err.Error()},
+		}
+	}
+	result, rerr := s.dispatch(ctx, req)
+	if len(req.ID) == 0 {
+		return nil
+	}
+	return &rpcResponse{
+		JSONRPC: "2.0",
+		ID:      req.ID,
+		Result:  result,
+		Error:   rerr,
+	}
+}
+
+func (s *mcpServer) dispatch(ctx context.Context, req rpcRequest) (any, *rpcError) {
+	switch req.Method {
+	case "initialize":
+		var params struct {
+			ProtocolVersion string `json:"protocolVersion"`
+		}
+		_ = json.Unmarshal(req.Params, &params)
+		version := mcpProtocolVersion
+		if slices.Contains(mcpProtocolVersions, params.ProtocolVersion) {
+			version = params.ProtocolVersion
+		}
+		return map[string]any{
+			"protocolVersion": version,
+			"capabilities": map[string]any{
+				"tools": map[string]any{},
+			},
+			"serverInfo": map[string]any{
+				"name":    s.root.Name(),
+				"version": s.root.Version,
+			},
+		}, nil
+	case "ping":
+		return map[string]any{}, nil
+	case "tools/list":
+		return map[string]any{"tools": s.tools}, nil
+	case "tools/call":
+		var params struct {
+			Name      string         `json:"name"`
+			Arguments map[string]any `json:"arguments"`
+		}
+		dec := json.NewDecoder(bytes.NewReader(req.Params))
+		dec.UseNumber()
+		if err := dec.Decode(&params); err != nil {
+			return nil, &rpcError{rpcInvalidParams, err.Error()}
+		}
+		i := slices.IndexFunc(s.tools, func(t mcpTool) bool {
+			return t.Name == params.Name
+		})
+		if i < 0 {
+			return nil, &rpcError{rpcInvalidParams, fmt.Sprintf("unknown tool %q", params.Name)}
+		}
+		args, err := mcpArgs(s.tools[i].cmd, params.Arguments)
+		if err != nil {
+			return nil, &rpcError{rpcInvalidParams, err.Error()}
+		}
+		return s.call(ctx, args), nil
+	}
+	if strings.HasPrefix(req.Method, "notifications/") {
+		return nil, nil
+	}
+	return nil, &rpcError{rpcMethodNotFound, fmt.Sprintf("method %q not found", req.Method)}
+}
+
+// call runs the program with the given arguments, returning everything it
+// printed.
+func (s *mcpServer) call(ctx context.Context, args []string) mcpCallResult {
+	walk(s.root, resetFlags)
+
+	output, err := captureOutput(func(stdin io.Reader, w io.Writer) error {
+		s.root.SetIn(stdin)
+		s.root.SetOut(w)
+		s.root.SetErr(w)
+		defer func() {
+			s.root.SetIn(s.in)
+			s.root.SetOut(s.out)
+			s.root.SetErr(s.err)
+		}()
+		s.root.SetArgs(args)
+		//nolint:wrapcheck
+		return s.root.ExecuteContext(ctx)
+	})
+	if errors.Is(err, errSkipRun) {
+		err = nil
+	}
+
+	result := mcpCallResult{IsError: err != nil}
+	if err != nil {
+		// redacted as in Execute, as the error may hold the values of
+		// sensitive flags.
+		redactor := newRedactor(s.root, s.redactPatterns)
+		output += "Error: " + redactor.redact(err.Error()) + "\n"
+	} else if d := dryRunFrom(ctx); d != nil {
+		var summary strings.Builder
+		d.writePlainSummary(&summary)
+		output += summary.String()
+	}
+	result.Content = append(result.Content, mcpContent{"text", output})
+	return result
+}
+
+// captureOutput calls fn with an empty input and a writer, and with os.Stdin,
+// os.Stdout and os.Stderr redirected, so that nothing it prints ends up in
+// our output. It returns everything fn printed.
+func captureOutput(fn func(io.Reader, io.Writer) error) (string, error) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		return "", fmt.Errorf("could not capture output: %w", err)
+	}
+	defer r.Close() //nolint:errcheck
+
+	stdin, err := os.Open(os.DevNull)
+	if err != nil {
+		_ = w.Close()
+		return "", fmt.Errorf("could not capture output: %w", err)
+	}
+	defer stdin.Close() //nolint:errcheck
+
+	var buf bytes.Buffer
+	done := make(chan struct{})
+	go func() {
+		_, _ = io.Copy(&buf, r)
+		close(done)
+	}()
+
+	err = func() error {
+		oldIn, oldOut, oldErr := os.Stdin, os.Stdout, os.Stderr
+		os.Stdin, os.Stdout, os.Stderr = stdin, w, w
+		defer func() {
+			os.Stdin, os.Stdout, os.Stderr = oldIn, oldOut, oldErr
+			_ = w.Close()
+		}()
+		return fn(stdin, w)
+	}()
+	<-done
+	return buf.String(), err
+}
+
+// resetFlags sets the flags of the given command back to their defaults, as
+// the program may run more than once.
+func resetFlags(c *cobra.Command) {
+	for _, fs := range []*pflag.FlagSet{c.Flags(), c.PersistentFlags()} {
+		fs.VisitAll(func(f *pflag.Flag) {
+			if sv, ok := f.Value.(pflag.SliceValue); ok {
+				var values []string
+				if s := strings.Trim(f.DefValue, "[]"); s != "" {
+					values = strings.Split(s, ",")
+				}
+				_ = sv.Replace(values)
+			} else {
+				_ = f.Value.Set(f.DefValue)
+			}
+			f.Changed = false
+		})
+	}
+}
+
+// mcpTools returns the runnable leaf commands exposed as tools.
+func mcpTools(root *cobra.Command) []mcpTool {
+	var tools []mcpTool
+	walk(root, func(c *cobra.Command) {
+		if !c.Runnable() || slices.ContainsFunc(c.Commands(), isMCPCommand) || !isMCPCommand(c) {
+			return
+		}
+		tools = append(tools, mcpTool{
+			Name:        mcpToolName(c),
+			Description: mcpToolDescription(c),
+			InputSchema: mcpInputSchema(c),
+			Annotations: mcpAnnotations(c),
+			cmd:         c,
+		})
+	})
+	return tools
+}
+
+// isMCPCommand reports whether the given command may be exposed as a tool.
+func isMCPCommand(c *cobra.Command) bool {
+	hidden := false
+	for p := c; p != nil; p = p.Parent() {
+		switch p.Annotations[MCPAnnotation] {
+		case MCPAllow:
+			return true
+		case MCPDeny:
+			return false
+		}
+		if isDefaultCommand(p) {
+			return false
+		}
+		hidden = hidden || p.Hidden
+	}
+	return !hidden && !isDestructive(c)
+}
+
+// mcpAnnotations returns the hints about the tool of the given command.
+func mcpAnnotations(c *cobra.Command) *mcpToolAnnotations {
+	if !isDestructive(c) {
+		return nil
+	}
+	return &mcpToolAnnotations{DestructiveHint: true}
+}
+
+var mcpToolNameInvalid = regexp.MustCompile(`[^a-zA-Z0-9_-]`)
+
+func mcpToolName(c *cobra.Command) string {
+	path := strings.Fields(c.CommandPath())
+	if len(path) > 1 {
+		path = path[1:]
+	}
+	return mcpToolNameInvalid.ReplaceAllString(strings.Join(path, "_"), "_")
+}
+
+func mcpToolDescription(c *cobra.Command) string {
+	desc := c.Short
+	if c.Long != "" {
+		desc = c.Long
+	}
+	if lines := exampleLines(c.Example); len(lines) > 0 {
+		desc += "\n\nExamples:\n" + strings.Join(lines, "\n")
+	}
+	return strings.TrimSpace(desc)
+}
+
+// mcpInputSchema returns the JSON schema of the input of the given command:
+// its flags by name, and its positional arguments in "args".
+func mcpInputSchema(c *cobra.Command) map[string]any {
+	props := map[string]any{}
+	var required []string
+	visitAllFlags(c, func(f *pflag.Flag) {
+		if f.Hidden || isDefaultFlag(f.Name) || isYesFlag(f) {
+			return
+		}
+		props[f.Name] = flagSchema(f)
+		if isRequired(f) {
+			required = append(required, f.Name)
+		}
+	})
+
+	if args := useArgs(c); len(args) > 0 {
+		names := make([]string, 0, len(args))
+		var minItems int
+		variadic := false
+		for _, arg := range args {
+			names = append(names, argString(arg))
+			if arg.Required {
+				minItems++
+			}
+			variadic = variadic || arg.Variadic
+		}
+		schema := map[string]any{
+			"type":        "array",
+			"items":       map[string]any{"type": "string"},
+			"description": "Positional arguments: " + strings.Join(names, " "),
+		}
+		if values := argEnum(c); len(values) > 0 {
+			schema["items"] = map[string]any{"type": "string", "enum": values}
+		}
+		if !variadic {
+			schema["maxItems"] = len(args)
+		}
+		if minItems > 0 {
+			schema["minItems"] = minItems
+			required = append(required, "args")
+		}
+		props["args"] = schema
+	}
+
+	schema := map[string]any{
+		"type":       "object",
+		"properties": props,
+	}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+func flagSchema(f *pflag.Flag) map[string]any {
+	_, usage := flagValueName(f)
+	if hasDefault(f) && !isSensitive(f) {
+		usage += " (default: " + f.DefValue + ")"
+	}
+	typ := f.Value.Type()
+	schema := map[string]any{
+		"type":        jsonType(strings.TrimSuffix(strings.TrimSuffix(typ, "Slice"), "Array")),
+		"description": strings.TrimSpace(usage),
+	}
+	if values := flagEnum(f); len(values) > 0 {
+		schema["enum"] = values
+	}
+	if isRepeatable(f) && typ != "count" {
+		schema = map[string]any{
+			"type":        "array",
+			"items":       map[string]any{"type": schema["type"]},
+			"description": schema["description"],
+		}
+	}
+	return schema
+}
+
+// jsonType returns the JSON schema type of the given flag type.
+func jsonType(typ string) string {
+	switch typ {
+	case "bool":
+		return "boolean"
+	case "int", "int8", "int16", "int32", "int64",
+		"uint", "uint8", "uint16", "uint32", "uint64", "count":
+		return "integer"
+	case "float32", "float64":
+		return "number"
+	}
+	return "string"
+}
+
+// mcpArgs returns the command line running the given command with the given
+// tool arguments.
+func mcpArgs(c *cobra.Command, arguments map[string]any) ([]string, error) {
+	args := strings.Fields(c.CommandPath())[1:]
+	var positional []string
+	names := make([]string, 0, len(arguments))
+	for name := range arguments {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		value := arguments[name]
+		if name == "args" && lookupFlag(c, name, "") == nil {
+			values, ok := value.([]any)
+			if !ok {
+				return nil, errors.New("args must be an array")
+			}
+			for _, v := range values {
+				positional = append(positional, jsonString(v))
+			}
+			continue
+		}
+		if f := lookupFlag(c, name, ""); f == nil || f.Hidden || isYesFlag(f) {
+			return nil, fmt.Errorf("unknown argument %q", name)
+		}
+		values, ok := value.([]any)
+		if !ok {
+			values = []any{value}
+		}
+		for _, v := range values {
+			args = append(args, "--"+name+"="+jsonString(v))
+		}
+	}
+	if f := c.Flags().Lookup(yesFlag); f != nil && isYesFlag(f) {
+		// the client asks for a confirmation, see mcpAnnotations.
+		args = append(args, "--"+yesFlag)
+	}
+	if len(positional) > 0 {
+		args = append(append(args, "--"), positional...)
+	}
+	return args, nil
+}
+
+func jsonString(v any) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	case bool:
+		return fmt.Sprint(v)
+	}
+	bts, _ := json.Marshal(v)
+	return string(bts)
+}
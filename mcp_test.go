@@ -0,0 +1,230 @@
+package fang_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"testing"
+
+	"charm.land/fang/v2"
+	"github.com/charmbracelet/x/exp/golden"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMCP(t *testing.T) {
+	root := &cobra.Command{Use: "app", Short: "A sample app"}
+	root.PersistentFlags().BoolP("verbose", "v", false, "Verbose output")
+	get := &cobra.Command{
+		Use:     "get <id>",
+		Short:   "Get an item",
+		Example: "app get 10 --output json",
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			output, _ := cmd.Flags().GetString("output")
+			labels, _ := cmd.Flags().GetStringSlice("label")
+			verbose, _ := cmd.Flags().GetBool("verbose")
+			cmd.Printf("item %s as %s\n", args[0], output)
+			// printed straight to stdout, which is our output.
+			fmt.Printf("labels=%v verbose=%v\n", labels, verbose)
+			return nil
+		},
+	}
+	get.Flags().StringP("output", "o", "text", "Output format")
+	get.Flags().StringSlice("label", nil, "Filter by label")
+	_ = fang.MarkFlagEnum(get.Flags(), "output", "text", "json")
+	admin := &cobra.Command{
+		Use:         "admin",
+		Annotations: map[string]string{fang.MCPAnnotation: fang.MCPDeny},
+	}
+	admin.AddCommand(&cobra.Command{Use: "wipe", Run: func(*cobra.Command, []string) {}})
+	drop := &cobra.Command{
+		Use:   "drop <name>",
+		Short: "Drop a database",
+		Args:  cobra.ExactArgs(1),
+		Annotations: map[string]string{
+			fang.DestructiveAnnotation: "true",
+			fang.MCPAnnotation:         fang.MCPAllow,
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmd.Printf("dropped %s\n", args[0])
+			return nil
+		},
+	}
+	purge := &cobra.Command{
+		Use:         "purge",
+		Short:       "Purge the caches",
+		Annotations: map[string]string{fang.DestructiveAnnotation: "true"},
+		Run:         func(*cobra.Command, []string) {},
+	}
+	root.AddCommand(
+		get,
+		admin,
+		drop,
+		purge,
+		&cobra.Command{Use: "debug", Hidden: true, Run: func(*cobra.Command, []string) {}},
+	)
+
+	requests := []string{
+		`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-03-26","capabilities":{},"clientInfo":{"name":"test","version":"1"}}}`,
+		`{"jsonrpc":"2.0","method":"notifications/initialized"}`,
+		`{"jsonrpc":"2.0","id":2,"method":"tools/list"}`,
+		`{"jsonrpc":"2.0","id":3,"method":"tools/call","params":{"name":"get","arguments":{"args":["10"],"output":"json","label":["a","b"],"verbose":true}}}`,
+		`{"jsonrpc":"2.0","id":4,"method":"tools/call","params":{"name":"get","arguments":{"args":["20"]}}}`,
+		`{"jsonrpc":"2.0","id":5,"method":"tools/call","params":{"name":"get","arguments":{}}}`,
+		`{"jsonrpc":"2.0","id":6,"method":"tools/call","params":{"name":"admin_wipe","arguments":{}}}`,
+		`{"jsonrpc":"2.0","id":7,"method":"tools/call","params":{"name":"get","arguments":{"nope":1}}}`,
+		`{"jsonrpc":"2.0","id":8,"method":"resources/list"}`,
+		`{"jsonrpc":"2.0","id":9,"method":"ping"}`,
+		`{"jsonrpc":"2.0","id":10,"method":"tools/call","params":{"name":"drop","arguments":{"args":["prod"]}}}`,
+		`{"jsonrpc":"2.0","id":11,"method":"tools/call","params":{"name":"drop","arguments":{"args":["prod"],"yes":true}}}`,
+		`{"jsonrpc":"2.0","id":12,"method":"tools/call","params":{"name":"purge","arguments":{}}}`,
+		`not json`,
+	}
+
+	var stdout, stderr bytes.Buffer
+	root.SetIn(strings.NewReader(strings.Join(requests, "\n") + "\n"))
+	root.SetOut(&stdout)
+	root.SetErr(&stderr)
+	root.SetArgs([]string{"mcp"})
+	require.NoError(t, fang.Execute(t.Context(), root, fang.WithMCP(), fang.WithVersion("1.0.0")))
+	require.Empty(t, stderr.String())
+	golden.RequireEqual(t, stdout.Bytes())
+}
+
+func TestMCPShowConfig(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	root := &cobra.Command{Use: "app", Short: "A sample app"}
+	get := &cobra.Command{
+		Use:   "get",
+		Short: "Get an item",
+		Run:   func(*cobra.Command, []string) {},
+	}
+	get.Flags().String("output", "text", "Output format")
+	root.AddCommand(get)
+
+	var stdout bytes.Buffer
+	root.SetIn(strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"get","arguments":{"show-config":true}}}` + "\n"))
+	root.SetOut(&stdout)
+	root.SetErr(io.Discard)
+	root.SetArgs([]string{"mcp"})
+	require.NoError(t, fang.Execute(t.Context(), root, fang.WithMCP(), fang.WithConfig()))
+
+	var res struct {
+		Result struct {
+			Content []struct {
+				Text string `json:"text"`
+			} `json:"content"`
+			IsError bool `json:"isError"`
+		} `json:"result"`
+	}
+	require.NoError(t, json.Unmarshal(stdout.Bytes(), &res))
+	require.False(t, res.Result.IsError)
+	require.Contains(t, res.Result.Content[0].Text, "--output")
+}
+
+func TestMCPConfigCommand(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	root := &cobra.Command{Use: "app", Short: "A sample app"}
+	root.AddCommand(&cobra.Command{Use: "get", Short: "Get an item", Run: func(*cobra.Command, []string) {}})
+
+	var stdout bytes.Buffer
+	root.SetIn(strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"tools/list"}` + "\n"))
+	root.SetOut(&stdout)
+	root.SetErr(io.Discard)
+	root.SetArgs([]string{"mcp"})
+	require.NoError(t, fang.Execute(t.Context(), root, fang.WithMCP(), fang.WithConfigCommand()))
+
+	var res struct {
+		Result struct {
+			Tools []struct {
+				Name string `json:"name"`
+			} `json:"tools"`
+		} `json:"result"`
+	}
+	require.NoError(t, json.Unmarshal(stdout.Bytes(), &res))
+	var names []string
+	for _, tool := range res.Result.Tools {
+		names = append(names, tool.Name)
+	}
+	require.Equal(t, []string{"get"}, names)
+}
+
+func TestMCPDryRun(t *testing.T) {
+	root := &cobra.Command{Use: "app", Short: "A sample app"}
+	root.AddCommand(&cobra.Command{
+		Use:         "clean",
+		Short:       "Remove the old releases",
+		Annotations: map[string]string{fang.DryRunAnnotation: "true"},
+		Run: func(cmd *cobra.Command, _ []string) {
+			if fang.IsDryRun(cmd.Context()) {
+				fang.RecordAction(cmd.Context(), "Remove release v1.0.0")
+				return
+			}
+			cmd.Println("removed v1.0.0")
+		},
+	})
+
+	requests := []string{
+		`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"clean","arguments":{"dry-run":true}}}`,
+		`{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"clean","arguments":{}}}`,
+	}
+	var stdout, stderr bytes.Buffer
+	root.SetIn(strings.NewReader(strings.Join(requests, "\n") + "\n"))
+	root.SetOut(&stdout)
+	root.SetErr(&stderr)
+	root.SetArgs([]string{"mcp"})
+	require.NoError(t, fang.Execute(t.Context(), root, fang.WithMCP(), fang.WithDryRun()))
+	require.Empty(t, stderr.String())
+
+	var texts []string
+	dec := json.NewDecoder(&stdout)
+	for dec.More() {
+		var res struct {
+			Result struct {
+				Content []struct {
+					Text string `json:"text"`
+				} `json:"content"`
+			} `json:"result"`
+		}
+		require.NoError(t, dec.Decode(&res))
+		texts = append(texts, res.Result.Content[0].Text)
+	}
+	require.Equal(t, []string{
+		"\nDry run:\nAction: Remove release v1.0.0.\nNothing was done, as --dry-run is set.\n",
+		"removed v1.0.0\n",
+	}, texts)
+}
+
+func TestMCPRedact(t *testing.T) {
+	root := &cobra.Command{Use: "app", Short: "A sample app"}
+	login := &cobra.Command{
+		Use:   "login",
+		Short: "Log in",
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			token, _ := cmd.Flags().GetString("token")
+			return fmt.Errorf("invalid token %s for session sess_0123456789", token)
+		},
+	}
+	login.Flags().String("token", "", "API token")
+	_ = fang.MarkFlagSensitive(login.Flags(), "token")
+	root.AddCommand(login)
+
+	var stdout bytes.Buffer
+	root.SetIn(strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"login","arguments":{"token":"t0k3n-s3cr3t"}}}` + "\n"))
+	root.SetOut(&stdout)
+	root.SetErr(io.Discard)
+	root.SetArgs([]string{"mcp"})
+	require.NoError(t, fang.Execute(
+		t.Context(), root,
+		fang.WithMCP(),
+		fang.WithRedactPatterns(regexp.MustCompile(`sess_\w+`)),
+	))
+
+	require.NotContains(t, stdout.String(), "t0k3n-s3cr3t")
+	require.NotContains(t, stdout.String(), "sess_0123456789")
+	require.Contains(t, stdout.String(), "invalid token ***")
+}
@@ -0,0 +1,265 @@
+package fang
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/colorprofile"
+	"github.com/charmbracelet/x/term"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// isInteractive reports whether the user can answer prompts, i.e. whether
+// both the input and the error output of the given command are terminals.
+//
+// It's a variable so tests can pretend to be interactive.
+var isInteractive = func(c *cobra.Command) bool {
+	in, ok := c.InOrStdin().(term.File)
+	if !ok || !term.IsTerminal(in.Fd()) {
+		return false
+	}
+	out, ok := c.ErrOrStderr().(term.File)
+	return ok && term.IsTerminal(out.Fd())
+}
+
+// prompter asks the user for values, one line at a time.
+type prompter struct {
+	in     io.Reader
+	w      io.Writer
+	styles Styles
+}
+
+func newPrompter(c *cobra.Command, styles Styles) prompter {
+	return prompter{
+		in:     c.InOrStdin(),
+		w:      colorprofile.NewWriter(c.ErrOrStderr(), os.Environ()),
+		styles: styles,
+	}
+}
+
+// ask writes the given question, and returns the answer of the user, without
+// echoing it if secret is set and the input is a terminal.
+func (p prompter) ask(question, hint string, secret bool) (string, error) {
+	prompt := p.styles.Program.Command.Render("?") + " " + p.styles.Text.Render(question)
+	if hint != "" {
+		prompt += " " + p.styles.FlagDefault.Render(hint)
+	}
+	_, _ = fmt.Fprint(p.w, prompt+" ")
+	if f, ok := p.in.(term.File); ok && secret && term.IsTerminal(f.Fd()) {
+		bts, err := term.ReadPassword(f.Fd())
+		_, _ = fmt.Fprintln(p.w)
+		if err != nil {
+			return "", fmt.Errorf("could not read answer: %w", err)
+		}
+		return strings.TrimSpace(string(bts)), nil
+	}
+	return p.line()
+}
+
+// line reads a line, one byte at a time, so nothing after it is consumed.
+func (p prompter) line() (string, error) {
+	var sb strings.Builder
+	buf := make([]byte, 1)
+	for {
+		n, err := p.in.Read(buf)
+		if n > 0 {
+			if buf[0] == '\n' {
+				return strings.TrimSpace(sb.String()), nil
+			}
+			sb.WriteByte(buf[0])
+		}
+		if errors.Is(err, io.EOF) {
+			if sb.Len() == 0 {
+				_, _ = fmt.Fprintln(p.w)
+				return "", errors.New("no answer given")
+			}
+			return strings.TrimSpace(sb.String()), nil
+		}
+		if err != nil {
+			return "", fmt.Errorf("could not read answer: %w", err)
+		}
+	}
+}
+
+// text asks for a non-empty value.
+func (p prompter) text(question string, secret bool) (string, error) {
+	for {
+		answer, err := p.ask(question, "", secret)
+		if err != nil || answer != "" {
+			return answer, err
+		}
+	}
+}
+
+// choose asks to pick one of the given options, by number or by value.
+func (p prompter) choose(question string, options []string) (string, error) {
+	_, _ = fmt.Fprintln(p.w, p.styles.Program.Command.Render("?")+" "+p.styles.Text.Render(question))
+	for i, option := range options {
+		_, _ = fmt.Fprintln(p.w, strings.Repeat(" ", longPad)+
+			p.styles.FlagDefault.Render(strconv.Itoa(i+1)+".")+" "+
+			p.styles.Program.Argument.Render(option))
+	}
+	for {
+		answer, err := p.ask("Choose", fmt.Sprintf("[1-%d]", len(options)), false)
+		if err != nil {
+			return "", err
+		}
+		if i, err := strconv.Atoi(answer); err == nil && i >= 1 && i <= len(options) {
+			return options[i-1], nil
+		}
+		if i := slices.IndexFunc(options, func(o string) bool { return strings.EqualFold(o, answer) }); i >= 0 {
+			return options[i], nil
+		}
+	}
+}
+
+// confirm asks a yes or no question.
+func (p prompter) confirm(question string, yes bool) (bool, error) {
+	hint := "(y/N)"
+	if yes {
+		hint = "(Y/n)"
+	}
+	for {
+		answer, err := p.ask(question, hint, false)
+		if err != nil {
+			return false, err
+		}
+		switch strings.ToLower(answer) {
+		case "":
+			return yes, nil
+		case "y", "yes":
+			return true, nil
+		case "n", "no":
+			return false, nil
+		}
+	}
+}
+
+// promptFlag asks for the value of the given flag, with the prompt fitting its
+// type.
+func (p prompter) promptFlag(f *pflag.Flag) (string, error) {
+	question := "--" + f.Name
+	if usage := strings.TrimSpace(f.Usage); usage != "" {
+		question = titleFirstWord(usage) + " (--" + f.Name + ")"
+	}
+	switch {
+	case len(flagEnum(f)) > 0:
+		return p.choose(question, flagEnum(f))
+	case f.Value.Type() == "bool":
+		yes, err := p.confirm(question+"?", f.DefValue == "true")
+		return strconv.FormatBool(yes), err
+	default:
+		return p.text(question+":", isSensitive(f))
+	}
+}
+
+// promptFlags asks for the values of the required flags of the given command
+// which are not set, if the session is interactive.
+func promptFlags(styles func() Styles) func(*cobra.Command, []string) error {
+	return func(c *cobra.Command, _ []string) error {
+		if !isInteractive(c) {
+			return nil
+		}
+		var p *prompter
+		var err error
+		c.Flags().VisitAll(func(f *pflag.Flag) {
+			if err != nil || f.Changed || f.Hidden || !isRequired(f) {
+				return
+			}
+			if p == nil {
+				pr := newPrompter(c, styles())
+				p = &pr
+			}
+			var value string
+			if value, err = p.promptFlag(f); err != nil {
+				err = fmt.Errorf("could not get --%s: %w", f.Name, err)
+				return
+			}
+			if serr := c.Flags().Set(f.Name, value); serr != nil {
+				err = fmt.Errorf("invalid value for --%s: %w", f.Name, serr)
+			}
+		})
+		return err
+	}
+}
+
+// promptArgs makes the commands of the given tree ask for their missing
+// required positional arguments, as named in their Use line, when their Args
+// validator fails and the session is interactive.
+//
+// As cobra passes the arguments it validated to the hooks, the hooks of the
+// commands are wrapped to get the complete arguments.
+func promptArgs(root *cobra.Command, styles func() Styles) {
+	prompted := map[*cobra.Command][]string{}
+	withArgs := func(
+		hookE func(*cobra.Command, []string) error,
+		hook func(*cobra.Command, []string),
+	) func(*cobra.Command, []string) error {
+		if hookE == nil && hook == nil {
+			return nil
+		}
+		return func(c *cobra.Command, args []string) error {
+			if full, ok := prompted[c]; ok {
+				args = full
+			}
+			if hookE != nil {
+				return hookE(c, args)
+			}
+			hook(c, args)
+			return nil
+		}
+	}
+
+	walk(root, func(c *cobra.Command) {
+		c.PersistentPreRunE, c.PersistentPreRun = withArgs(c.PersistentPreRunE, c.PersistentPreRun), nil
+		c.PreRunE, c.PreRun = withArgs(c.PreRunE, c.PreRun), nil
+		c.RunE, c.Run = withArgs(c.RunE, c.Run), nil
+		c.PostRunE, c.PostRun = withArgs(c.PostRunE, c.PostRun), nil
+		c.PersistentPostRunE, c.PersistentPostRun = withArgs(c.PersistentPostRunE, c.PersistentPostRun), nil
+
+		validate := c.Args
+		var required []ArgSpec
+		for _, arg := range useArgs(c) {
+			if arg.Required {
+				required = append(required, arg)
+			}
+		}
+		if validate == nil || len(required) == 0 || !c.Runnable() {
+			return
+		}
+		c.Args = func(c *cobra.Command, args []string) error {
+			delete(prompted, c)
+			err := validate(c, args)
+			if err == nil || len(args) >= len(required) || !isInteractive(c) {
+				return err
+			}
+			p := newPrompter(c, styles())
+			values := argEnum(c)
+			args = slices.Clone(args)
+			for _, arg := range required[len(args):] {
+				var answer string
+				var perr error
+				if len(values) > 0 {
+					answer, perr = p.choose(titleFirstWord(arg.Name)+":", values)
+				} else {
+					answer, perr = p.text(titleFirstWord(arg.Name)+":", false)
+				}
+				if perr != nil {
+					return fmt.Errorf("could not get %s: %w", arg.Name, perr)
+				}
+				args = append(args, answer)
+			}
+			if err := validate(c, args); err != nil {
+				return err
+			}
+			prompted[c] = args
+			return nil
+		}
+	})
+}
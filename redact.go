@@ -0,0 +1,157 @@
+package fang
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+const sensitiveAnnotation = "fang_sensitive"
+
+// MarkFlagSensitive marks the flag with the given name in the given flag set
+// as holding a secret, e.g. a token.
+//
+// Its default is shown as "(set)" in the help, and left out of generated
+// specs, and its value is redacted from errors and crash reports.
+//
+// Example:
+//
+//	cmd.Flags().String("token", os.Getenv("APP_TOKEN"), "API token")
+//	_ = fang.MarkFlagSensitive(cmd.Flags(), "token")
+func MarkFlagSensitive(flags *pflag.FlagSet, name string) error {
+	//nolint:wrapcheck
+	return flags.SetAnnotation(name, sensitiveAnnotation, []string{"true"})
+}
+
+// isSensitive reports whether the given flag was marked with
+// [MarkFlagSensitive].
+func isSensitive(f *pflag.Flag) bool {
+	return len(f.Annotations[sensitiveAnnotation]) > 0
+}
+
+// secretFlag matches the names of flags whose values are redacted even
+// if they were not marked with [MarkFlagSensitive].
+var secretFlag = regexp.MustCompile(`(?i)(token|secret|passw(or)?d|api[-_]?key|(^|[-_])auth(entication|orization)?([-_]|$)|credential|private[-_]?key)`)
+
+// redactArgs returns the given arguments of the given command with the values
+// of its sensitive flags, and of the flags that look like they hold secrets,
+// replaced.
+func redactArgs(c *cobra.Command, args []string) []string {
+	redacted := make([]string, 0, len(args))
+	next := false
+	for _, arg := range args {
+		if next {
+			redacted = append(redacted, redactedValue)
+			next = false
+			continue
+		}
+		if arg == "--" {
+			redacted = append(redacted, args[len(redacted):]...)
+			break
+		}
+		name, _, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		if strings.HasPrefix(arg, "-") && isSecretFlag(c, name) {
+			switch f := argFlag(c, name); {
+			case hasValue:
+				arg = arg[:strings.Index(arg, "=")+1] + redactedValue
+			case f == nil || f.NoOptDefVal == "":
+				next = true
+			}
+		}
+		redacted = append(redacted, arg)
+	}
+	return redacted
+}
+
+const redactedValue = "***"
+
+// minSecretLen is the length under which the values of sensitive flags are
+// not redacted, as they would likely match unrelated text.
+const minSecretLen = 4
+
+// redactor redacts secrets from text.
+type redactor struct {
+	secrets  []string
+	patterns []*regexp.Regexp
+}
+
+// newRedactor returns a redactor for the values of the sensitive flags of the
+// given command tree, if any, and the given patterns.
+func newRedactor(root *cobra.Command, patterns []*regexp.Regexp) redactor {
+	r := redactor{patterns: patterns}
+	if root == nil {
+		return r
+	}
+	walk(root, func(c *cobra.Command) {
+		visitAllFlags(c, func(f *pflag.Flag) {
+			if !isSensitive(f) {
+				return
+			}
+			for _, v := range []string{f.DefValue, f.Value.String()} {
+				if utf8.RuneCountInString(v) >= minSecretLen {
+					r.secrets = append(r.secrets, v)
+				}
+			}
+		})
+	})
+	// replace the longest first, in case some contain others.
+	sort.Slice(r.secrets, func(i, j int) bool {
+		return len(r.secrets[i]) > len(r.secrets[j])
+	})
+	return r
+}
+
+// redact replaces the secrets in the given text. Patterns with submatches
+// only have these replaced, e.g. `token=(\S+)` leaves "token=" alone.
+func (r redactor) redact(s string) string {
+	for _, secret := range r.secrets {
+		s = strings.ReplaceAll(s, secret, redactedValue)
+	}
+	for _, re := range r.patterns {
+		if re.NumSubexp() == 0 {
+			s = re.ReplaceAllLiteralString(s, redactedValue)
+			continue
+		}
+		s = re.ReplaceAllStringFunc(s, func(match string) string {
+			loc := re.FindStringSubmatchIndex(match)
+			var sb strings.Builder
+			last := 0
+			for i := 2; i < len(loc); i += 2 {
+				if loc[i] < last {
+					continue
+				}
+				sb.WriteString(match[last:loc[i]])
+				sb.WriteString(redactedValue)
+				last = loc[i+1]
+			}
+			sb.WriteString(match[last:])
+			return sb.String()
+		})
+	}
+	return s
+}
+
+// isSecretFlag reports whether the flag with the given name or shorthand of
+// the given command is sensitive, or looks like it is.
+func isSecretFlag(c *cobra.Command, name string) bool {
+	if f := argFlag(c, name); f != nil && isSensitive(f) {
+		return true
+	}
+	return secretFlag.MatchString(name)
+}
+
+// argFlag returns the flag of the given command with the given name or
+// shorthand, if any.
+func argFlag(c *cobra.Command, name string) *pflag.Flag {
+	if c == nil {
+		return nil
+	}
+	if len(name) == 1 {
+		return c.Flags().ShorthandLookup(name)
+	}
+	return c.Flags().Lookup(name)
+}
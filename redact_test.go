@@ -0,0 +1,52 @@
+package fang
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedactArgs(t *testing.T) {
+	c := &cobra.Command{Use: "deploy"}
+	c.Flags().StringP("key", "k", "", "")
+	c.Flags().Bool("auth-disabled", false, "")
+	c.Flags().String("author", "", "")
+	c.Flags().String("oauth-provider", "", "")
+	require.NoError(t, MarkFlagSensitive(c.Flags(), "key"))
+
+	require.Equal(
+		t,
+		[]string{"deploy", "--token", "***", "--api-key=***", "-k", "***", "--auth-disabled", "web", "--author", "jane", "--oauth-provider=github", "--authorization=***", "--", "--password", "x"},
+		redactArgs(c, []string{"deploy", "--token", "t0k3n", "--api-key=abc", "-k", "s3cr3t", "--auth-disabled", "web", "--author", "jane", "--oauth-provider=github", "--authorization=bearer", "--", "--password", "x"}),
+	)
+}
+
+func TestRedactor(t *testing.T) {
+	root := &cobra.Command{Use: "app"}
+	root.PersistentFlags().String("token", "default-token", "")
+	root.Flags().String("name", "my-name", "")
+	sub := &cobra.Command{Use: "sub"}
+	sub.Flags().String("password", "", "")
+	sub.Flags().String("pin", "", "")
+	root.AddCommand(sub)
+	require.NoError(t, MarkFlagSensitive(root.PersistentFlags(), "token"))
+	require.NoError(t, MarkFlagSensitive(sub.Flags(), "password"))
+	require.NoError(t, MarkFlagSensitive(sub.Flags(), "pin"))
+	require.NoError(t, sub.Flags().Set("password", "hunter2hunter2"))
+	require.NoError(t, sub.Flags().Set("pin", "123"))
+
+	r := newRedactor(root, []*regexp.Regexp{
+		regexp.MustCompile(`ghp_[A-Za-z0-9]+`),
+		regexp.MustCompile(`(?i)bearer (\S+)`),
+	})
+	for in, want := range map[string]string{
+		"login failed for my-name with default-token":   "login failed for my-name with ***",
+		"bad password hunter2hunter2, pin 123":          "bad password ***, pin 123",
+		"github said no to ghp_abc123":                  "github said no to ***",
+		"request with Authorization: Bearer abc failed": "request with Authorization: Bearer *** failed",
+	} {
+		require.Equal(t, want, r.redact(in))
+	}
+}
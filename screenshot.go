@@ -0,0 +1,442 @@
+package fang
+
+import (
+	"bytes"
+	"cmp"
+	"errors"
+	"fmt"
+	"html"
+	"image/color"
+	"io"
+	"strings"
+
+	"charm.land/lipgloss/v2"
+	"github.com/charmbracelet/colorprofile"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/spf13/cobra"
+)
+
+// ScreenshotFormat is the format of a screenshot.
+type ScreenshotFormat string
+
+// Screenshot formats.
+const (
+	ScreenshotSVG  ScreenshotFormat = "svg"
+	ScreenshotHTML ScreenshotFormat = "html"
+)
+
+const (
+	defaultScreenshotWidth = 80
+
+	// SVG geometry, in pixels.
+	svgFontSize   = 14
+	svgCellWidth  = 8.4 // 0.6em, the advance of most monospace fonts.
+	svgLineHeight = 18
+	svgPadding    = 16
+)
+
+// ScreenshotOptions are the options of [RenderHelp] and [RenderError].
+type ScreenshotOptions struct {
+	Format ScreenshotFormat // SVG or HTML, defaults to SVG.
+	Width  int              // the width of the terminal, defaults to 80.
+	Dark   bool             // use the dark variant of the colorscheme.
+}
+
+// RenderHelp renders the help of the given command to SVG or HTML, the way
+// fang prints it in a terminal with the given width, so it can be embedded
+// in docs and READMEs.
+//
+// The options are the ones given to [Execute], so the colorscheme and styles
+// match.
+func RenderHelp(w io.Writer, c *cobra.Command, opts ScreenshotOptions, options ...Option) error {
+	return renderScreenshot(w, opts, screenshotSettings(options), func(w *colorprofile.Writer, _ settings, styles Styles) {
+		helpFn(c, w, styles)
+	})
+}
+
+// RenderError renders the given error to SVG or HTML, the way the error
+// handler prints it in a terminal with the given width.
+//
+// The options are the ones given to [Execute], so the colorscheme, styles
+// and error handler match.
+func RenderError(w io.Writer, err error, opts ScreenshotOptions, options ...Option) error {
+	return renderScreenshot(w, opts, screenshotSettings(options), func(w *colorprofile.Writer, s settings, styles Styles) {
+		s.errHandler(w, styles, err)
+	})
+}
+
+func screenshotSettings(options []Option) settings {
+	s := settings{
+		colorscheme: DefaultColorScheme,
+		errHandler:  DefaultErrorHandler,
+	}
+	for _, option := range options {
+		option(&s)
+	}
+	return s
+}
+
+func renderScreenshot(
+	w io.Writer,
+	opts ScreenshotOptions,
+	s settings,
+	render func(*colorprofile.Writer, settings, Styles),
+) error {
+	c := lipgloss.LightDark(opts.Dark)
+	cs := s.colorscheme(c)
+	styles := s.styles(cs)
+	styles.width = cmp.Or(opts.Width, defaultScreenshotWidth)
+	styles.ErrorText = styles.ErrorText.Width(styles.width - 4)
+	styles.ErrorDetails = styles.ErrorDetails.Width(styles.width - 4)
+	styles.ErrorHint = styles.ErrorHint.Width(styles.width - 4)
+
+	var buf bytes.Buffer
+	render(&colorprofile.Writer{Forward: &buf, Profile: colorprofile.TrueColor}, s, styles)
+
+	screen := screenshot{
+		lines:      parseScreen(buf.String()),
+		width:      styles.width,
+		foreground: cs.Base,
+		background: c(lipgloss.Color("#FFFFFF"), lipgloss.Color("#000000")),
+	}
+	switch opts.Format {
+	case ScreenshotSVG, "":
+		return screen.writeSVG(w)
+	case ScreenshotHTML:
+		return screen.writeHTML(w)
+	}
+	return fmt.Errorf("unknown screenshot format %q", opts.Format)
+}
+
+// cellStyle is the style of a run of text, as set by SGR sequences.
+type cellStyle struct {
+	fg, bg                                          color.Color
+	bold, faint, italic, underline, strike, reverse bool
+}
+
+// run is a run of text with the same style.
+type run struct {
+	text  string
+	col   int // the column it starts at.
+	width int // the number of cells it takes.
+	style cellStyle
+}
+
+// parseScreen splits the given styled output into lines of runs.
+func parseScreen(s string) [][]run {
+	var (
+		lines [][]run
+		line  []run
+		style cellStyle
+		col   int
+		state byte
+	)
+	p := ansi.NewParser()
+	for len(s) > 0 {
+		seq, width, n, newState := ansi.DecodeSequence(s, state, p)
+		state = newState
+		s = s[n:]
+		switch {
+		case seq == "\n":
+			lines = append(lines, line)
+			line, col = nil, 0
+		case width > 0:
+			if len(line) > 0 && line[len(line)-1].style == style {
+				line[len(line)-1].text += seq
+				line[len(line)-1].width += width
+			} else {
+				line = append(line, run{seq, col, width, style})
+			}
+			col += width
+		case strings.HasPrefix(seq, "\x1b[") && ansi.Cmd(p.Command()) == 'm':
+			style = applySGR(style, p.Params())
+		}
+	}
+	if len(line) > 0 {
+		lines = append(lines, line)
+	}
+
+	// trim the trailing blanks of each line, and the leading and trailing
+	// blank lines.
+	for i, line := range lines {
+		for len(line) > 0 && line[len(line)-1].style.bg == nil {
+			last := &line[len(line)-1]
+			text := strings.TrimRight(last.text, " ")
+			if text != "" {
+				last.width -= len(last.text) - len(text)
+				last.text = text
+				break
+			}
+			line = line[:len(line)-1]
+		}
+		lines[i] = line
+	}
+	blank := func(line []run) bool {
+		for _, r := range line {
+			if strings.TrimSpace(r.text) != "" || r.style.bg != nil {
+				return false
+			}
+		}
+		return true
+	}
+	for len(lines) > 0 && blank(lines[0]) {
+		lines = lines[1:]
+	}
+	for len(lines) > 0 && blank(lines[len(lines)-1]) {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// applySGR applies the given SGR parameters to the given style.
+func applySGR(style cellStyle, params ansi.Params) cellStyle {
+	if len(params) == 0 {
+		return cellStyle{}
+	}
+	for i := 0; i < len(params); i++ {
+		switch n := params[i].Param(0); {
+		case n == 0:
+			style = cellStyle{}
+		case n == 1:
+			style.bold = true
+		case n == 2:
+			style.faint = true
+		case n == 3:
+			style.italic = true
+		case n == 4:
+			style.underline = true
+		case n == 7:
+			style.reverse = true
+		case n == 9:
+			style.strike = true
+		case n == 22:
+			style.bold, style.faint = false, false
+		case n == 23:
+			style.italic = false
+		case n == 24:
+			style.underline = false
+		case n == 27:
+			style.reverse = false
+		case n == 29:
+			style.strike = false
+		case n >= 30 && n <= 37:
+			style.fg = ansi.BasicColor(n - 30) //nolint:gosec
+		case n >= 40 && n <= 47:
+			style.bg = ansi.BasicColor(n - 40) //nolint:gosec
+		case n >= 90 && n <= 97:
+			style.fg = ansi.BasicColor(n - 90 + 8) //nolint:gosec
+		case n >= 100 && n <= 107:
+			style.bg = ansi.BasicColor(n - 100 + 8) //nolint:gosec
+		case n == 39:
+			style.fg = nil
+		case n == 49:
+			style.bg = nil
+		case n == 38 || n == 48 || n == 58:
+			var c color.Color
+			if read := ansi.ReadStyleColor(params[i:], &c); read > 0 {
+				i += read - 1
+				switch n {
+				case 38:
+					style.fg = c
+				case 48:
+					style.bg = c
+				}
+				continue
+			}
+		}
+		// skip sub-parameters, e.g. the underline style in 4:3.
+		for params[i].HasMore() && i+1 < len(params) {
+			i++
+		}
+	}
+	return style
+}
+
+// screenshot is a parsed terminal output, ready to be written as SVG or
+// HTML.
+type screenshot struct {
+	lines      [][]run
+	width      int
+	foreground color.Color
+	background color.Color
+}
+
+// colors returns the foreground and background colors of the given style,
+// with the defaults filled in and reverse applied.
+func (s screenshot) colors(style cellStyle) (color.Color, color.Color) {
+	fg, bg := style.fg, style.bg
+	if !isColor(fg) {
+		fg = s.foreground
+	}
+	if !isColor(bg) {
+		bg = nil
+	}
+	if style.reverse {
+		fg, bg = cmp.Or[color.Color](bg, s.background), fg
+	}
+	return fg, bg
+}
+
+func (s screenshot) writeSVG(w io.Writer) error {
+	width := float64(s.width)*svgCellWidth + 2*svgPadding
+	height := len(s.lines)*svgLineHeight + 2*svgPadding
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%.0f" height="%d" viewBox="0 0 %.0f %d">`+"\n", width, height, width, height)
+	fmt.Fprintf(&b, `<rect width="100%%" height="100%%" fill="%s"/>`+"\n", colorHex(s.background))
+	fmt.Fprintf(&b, `<g font-family="ui-monospace, SFMono-Regular, Menlo, Consolas, monospace" font-size="%d" xml:space="preserve">`+"\n", svgFontSize)
+	for i, line := range s.lines {
+		top := svgPadding + i*svgLineHeight
+		// merge the backgrounds of adjacent runs.
+		for j := 0; j < len(line); j++ {
+			_, bg := s.colors(line[j].style)
+			if bg == nil {
+				continue
+			}
+			col, cols := line[j].col, line[j].width
+			for j+1 < len(line) {
+				if _, next := s.colors(line[j+1].style); next == nil || colorHex(next) != colorHex(bg) {
+					break
+				}
+				j++
+				cols += line[j].width
+			}
+			fmt.Fprintf(&b, `<rect x="%.1f" y="%d" width="%.1f" height="%d" fill="%s"/>`+"\n",
+				svgPadding+float64(col)*svgCellWidth, top, float64(cols)*svgCellWidth, svgLineHeight, colorHex(bg))
+		}
+		for _, r := range line {
+			if strings.TrimSpace(r.text) == "" && !r.style.underline && !r.style.strike {
+				continue
+			}
+			fg, _ := s.colors(r.style)
+			fmt.Fprintf(&b, `<text x="%.1f" y="%d" fill="%s"%s>%s</text>`+"\n",
+				svgPadding+float64(r.col)*svgCellWidth, top+svgFontSize, colorHex(fg), svgAttrs(r.style), html.EscapeString(r.text))
+		}
+	}
+	b.WriteString("</g>\n</svg>\n")
+	_, err := io.WriteString(w, b.String())
+	//nolint:wrapcheck
+	return err
+}
+
+func svgAttrs(style cellStyle) string {
+	var attrs []string
+	if style.bold {
+		attrs = append(attrs, `font-weight="bold"`)
+	}
+	if style.italic {
+		attrs = append(attrs, `font-style="italic"`)
+	}
+	if style.faint {
+		attrs = append(attrs, `fill-opacity="0.5"`)
+	}
+	if decoration := textDecoration(style); decoration != "" {
+		attrs = append(attrs, `text-decoration="`+decoration+`"`)
+	}
+	if len(attrs) == 0 {
+		return ""
+	}
+	return " " + strings.Join(attrs, " ")
+}
+
+func (s screenshot) writeHTML(w io.Writer) error {
+	var b strings.Builder
+	fmt.Fprintf(&b,
+		`<pre style="background-color: %s; color: %s; width: %dch; padding: 1em; font-family: ui-monospace, SFMono-Regular, Menlo, Consolas, monospace; line-height: 1.3;">`,
+		colorHex(s.background), colorHex(s.foreground), s.width,
+	)
+	for i, line := range s.lines {
+		if i > 0 {
+			b.WriteByte('\n')
+		}
+		for _, r := range line {
+			text := html.EscapeString(r.text)
+			css := htmlCSS(s, r.style)
+			if css == "" {
+				b.WriteString(text)
+				continue
+			}
+			fmt.Fprintf(&b, `<span style="%s">%s</span>`, css, text)
+		}
+	}
+	b.WriteString("</pre>\n")
+	_, err := io.WriteString(w, b.String())
+	//nolint:wrapcheck
+	return err
+}
+
+func htmlCSS(s screenshot, style cellStyle) string {
+	var props []string
+	fg, bg := s.colors(style)
+	if colorHex(fg) != colorHex(s.foreground) {
+		props = append(props, "color: "+colorHex(fg))
+	}
+	if bg != nil {
+		props = append(props, "background-color: "+colorHex(bg))
+	}
+	if style.bold {
+		props = append(props, "font-weight: bold")
+	}
+	if style.italic {
+		props = append(props, "font-style: italic")
+	}
+	if style.faint {
+		props = append(props, "opacity: 0.5")
+	}
+	if decoration := textDecoration(style); decoration != "" {
+		props = append(props, "text-decoration: "+decoration)
+	}
+	return strings.Join(props, "; ")
+}
+
+func textDecoration(style cellStyle) string {
+	var decorations []string
+	if style.underline {
+		decorations = append(decorations, "underline")
+	}
+	if style.strike {
+		decorations = append(decorations, "line-through")
+	}
+	return strings.Join(decorations, " ")
+}
+
+func screenshotCmd(opts settings) *cobra.Command {
+	var (
+		format   string
+		width    int
+		dark     bool
+		errorMsg string
+	)
+	cmd := &cobra.Command{
+		Use:                   "screenshot [command]...",
+		Short:                 "Render the help of a command to SVG or HTML",
+		SilenceUsage:          true,
+		DisableFlagsInUseLine: true,
+		Hidden:                true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			screenshotOpts := ScreenshotOptions{
+				Format: ScreenshotFormat(format),
+				Width:  width,
+				Dark:   dark,
+			}
+			if errorMsg != "" {
+				return renderScreenshot(cmd.OutOrStdout(), screenshotOpts, opts, func(w *colorprofile.Writer, s settings, styles Styles) {
+					s.errHandler(w, styles, errors.New(errorMsg))
+				})
+			}
+			c, _, err := cmd.Root().Find(args)
+			if err != nil {
+				return fmt.Errorf("could not find command: %w", err)
+			}
+			return renderScreenshot(cmd.OutOrStdout(), screenshotOpts, opts, func(w *colorprofile.Writer, _ settings, styles Styles) {
+				helpFn(c, w, styles)
+			})
+		},
+	}
+	cmd.Flags().StringVarP(&format, "format", "f", string(ScreenshotSVG), "Output format")
+	cmd.Flags().IntVarP(&width, "width", "w", defaultScreenshotWidth, "Width of the terminal")
+	cmd.Flags().BoolVar(&dark, "dark", false, "Use the dark variant of the colorscheme")
+	cmd.Flags().StringVar(&errorMsg, "error", "", "Render this error `message` instead")
+	_ = MarkFlagEnum(cmd.Flags(), "format", string(ScreenshotSVG), string(ScreenshotHTML))
+	return cmd
+}
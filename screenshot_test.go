@@ -0,0 +1,59 @@
+package fang_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"charm.land/fang/v2"
+	"github.com/charmbracelet/x/exp/golden"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenderHelp(t *testing.T) {
+	cmd := &cobra.Command{
+		Use:     "app [args]",
+		Short:   "A sample app",
+		Example: `app --name "Carlos" <file.txt`,
+		Run:     func(*cobra.Command, []string) {},
+	}
+	cmd.Flags().StringP("name", "n", "fang", "Your name")
+
+	for name, opts := range map[string]fang.ScreenshotOptions{
+		"svg":  {Format: fang.ScreenshotSVG, Width: 60},
+		"html": {Format: fang.ScreenshotHTML, Width: 60, Dark: true},
+	} {
+		t.Run(name, func(t *testing.T) {
+			var buf bytes.Buffer
+			require.NoError(t, fang.RenderHelp(&buf, cmd, opts))
+			golden.RequireEqual(t, buf.Bytes())
+		})
+	}
+}
+
+func TestRenderError(t *testing.T) {
+	var buf bytes.Buffer
+	err := errors.New("something <went> wrong")
+	require.NoError(t, fang.RenderError(&buf, err, fang.ScreenshotOptions{Format: fang.ScreenshotHTML}))
+	golden.RequireEqual(t, buf.Bytes())
+
+	require.Error(t, fang.RenderError(&buf, err, fang.ScreenshotOptions{Format: "png"}))
+}
+
+func TestScreenshotCommand(t *testing.T) {
+	for name, args := range map[string][]string{
+		"help":  {"screenshot", "sub", "--width", "40"},
+		"error": {"screenshot", "--format", "html", "--error", "something went wrong"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			root := &cobra.Command{Use: "app", Short: "A sample app"}
+			root.AddCommand(&cobra.Command{Use: "sub", Short: "A subcommand", Run: func(*cobra.Command, []string) {}})
+			var stdout bytes.Buffer
+			root.SetOut(&stdout)
+			root.SetArgs(args)
+			require.NoError(t, fang.Execute(t.Context(), root, fang.WithScreenshotCommand()))
+			golden.RequireEqual(t, stdout.Bytes())
+		})
+	}
+}
@@ -0,0 +1,464 @@
+package fang
+
+import (
+	"strings"
+)
+
+// shellTokenKind is the kind of a token in a shell command line.
+type shellTokenKind int
+
+const (
+	shellSpace        shellTokenKind = iota // blanks between words.
+	shellWord                               // a word, made of one or more parts.
+	shellOperator                           // control operators, e.g. |, &&, ;.
+	shellRedirect                           // redirection operators, e.g. >, 2>&, <<.
+	shellComment                            // a comment, up to the end of the line.
+	shellContinuation                       // a backslash at the end of the line.
+	shellHeredoc                            // a line of a here-document body.
+	shellHeredocEnd                         // the line closing a here-document.
+)
+
+// shellPartKind is the kind of a part of a shell word.
+type shellPartKind int
+
+const (
+	shellPlain        shellPartKind = iota // unquoted text, including escapes.
+	shellSingleQuoted                      // '...'
+	shellDoubleQuoted                      // "..." without the expansions in it.
+	shellVariable                          // $NAME, ${NAME}.
+	shellSubstitution                      // $(...), $((...)), `...`.
+)
+
+// shellPart is a part of a shell word, e.g. `--name=` and `"value"` in
+// `--name="value"`.
+type shellPart struct {
+	kind shellPartKind
+	text string
+}
+
+// shellToken is a token of a shell command line.
+type shellToken struct {
+	kind  shellTokenKind
+	text  string
+	parts []shellPart // only set for words.
+}
+
+// value returns the value of a word, with quotes and escapes removed.
+// Expansions are kept as written, as their value is unknown.
+func (t shellToken) value() string {
+	var sb strings.Builder
+	for _, p := range t.parts {
+		switch p.kind {
+		case shellSingleQuoted:
+			sb.WriteString(strings.TrimSuffix(strings.TrimPrefix(p.text, "'"), "'"))
+		case shellDoubleQuoted:
+			s := strings.TrimSuffix(strings.TrimPrefix(p.text, `"`), `"`)
+			sb.WriteString(unescape(s, `$"\`+"`"))
+		case shellPlain:
+			sb.WriteString(unescape(p.text, ""))
+		default:
+			sb.WriteString(p.text)
+		}
+	}
+	return sb.String()
+}
+
+// quoted reports whether any part of the word is quoted.
+func (t shellToken) quoted() bool {
+	for _, p := range t.parts {
+		if p.kind == shellSingleQuoted || p.kind == shellDoubleQuoted {
+			return true
+		}
+	}
+	return false
+}
+
+// unescape removes backslashes escaping the given characters, or any
+// character if escapable is empty.
+func unescape(s, escapable string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) && (escapable == "" || strings.IndexByte(escapable, s[i+1]) >= 0) {
+			i++
+		}
+		sb.WriteByte(s[i])
+	}
+	return sb.String()
+}
+
+type heredoc struct {
+	delimiter string
+	stripTabs bool
+}
+
+// shellLexer splits shell command lines into tokens.
+//
+// It is fed one line at a time, and keeps the state needed to handle
+// constructs spanning multiple lines, like quoted strings and here-documents.
+type shellLexer struct {
+	quote    byte      // the quote left open by the previous line, if any.
+	pending  []heredoc // here-documents starting on the next line.
+	heredocs []heredoc // here-documents being read.
+
+	// state of the line being lexed.
+	line   string
+	pos    int
+	tokens []shellToken
+	word   []shellPart
+	start  int // start of the current word.
+
+	redirected bool // the last token is a here-document redirection.
+}
+
+// lex splits the given line into tokens.
+func (l *shellLexer) lex(line string) []shellToken {
+	if len(l.heredocs) > 0 {
+		return l.lexHeredoc(line)
+	}
+
+	l.line, l.pos, l.tokens, l.word, l.start = line, 0, nil, nil, 0
+	if l.quote != 0 {
+		q := l.quote
+		l.quote = 0
+		l.quoted(q, 0)
+	}
+
+	for l.pos < len(l.line) {
+		c := l.line[l.pos]
+		switch {
+		case c == ' ' || c == '\t':
+			l.endWord()
+			end := l.pos
+			for end < len(l.line) && (l.line[end] == ' ' || l.line[end] == '\t') {
+				end++
+			}
+			l.emit(shellSpace, l.line[l.pos:end])
+			l.pos = end
+		case c == '#' && len(l.word) == 0:
+			l.emit(shellComment, l.line[l.pos:])
+			l.pos = len(l.line)
+		case c == '\\' && l.pos == len(l.line)-1:
+			l.endWord()
+			l.emit(shellContinuation, `\`)
+			l.pos++
+		case c == '\\':
+			l.plain(l.pos + 2) //nolint:mnd
+		case c == '\'' || c == '"':
+			l.quoted(c, l.pos+1)
+		case c == '`':
+			l.part(shellSubstitution, l.closing(l.pos+1, '`'))
+		case c == '$':
+			l.dollar()
+		case c == '<' || c == '>':
+			l.redirect()
+		case c == '&' && l.pos+1 < len(l.line) && l.line[l.pos+1] == '>':
+			l.redirect()
+		case strings.IndexByte("|&;()", c) >= 0:
+			l.operator()
+		default:
+			l.plain(l.pos + 1)
+		}
+	}
+	l.endWord()
+
+	if l.quote == 0 && len(l.pending) > 0 {
+		for _, doc := range l.pending {
+			if doc.delimiter != "" {
+				l.heredocs = append(l.heredocs, doc)
+			}
+		}
+		l.pending = nil
+		l.redirected = false
+	}
+	return l.tokens
+}
+
+func (l *shellLexer) lexHeredoc(line string) []shellToken {
+	doc := l.heredocs[0]
+	delimiter := line
+	if doc.stripTabs {
+		delimiter = strings.TrimLeft(line, "\t")
+	}
+	if strings.TrimSpace(delimiter) == doc.delimiter {
+		l.heredocs = l.heredocs[1:]
+		return []shellToken{{kind: shellHeredocEnd, text: line}}
+	}
+	return []shellToken{{kind: shellHeredoc, text: line}}
+}
+
+func (l *shellLexer) emit(kind shellTokenKind, text string) {
+	l.tokens = append(l.tokens, shellToken{kind: kind, text: text})
+}
+
+// part adds the text from the current position up to end as a part of the
+// current word.
+func (l *shellLexer) part(kind shellPartKind, end int) {
+	end = min(end, len(l.line))
+	if len(l.word) == 0 {
+		l.start = l.pos
+	}
+	if n := len(l.word); n > 0 && l.word[n-1].kind == kind && kind == shellPlain {
+		l.word[n-1].text += l.line[l.pos:end]
+	} else {
+		l.word = append(l.word, shellPart{kind: kind, text: l.line[l.pos:end]})
+	}
+	l.pos = end
+}
+
+func (l *shellLexer) plain(end int) {
+	l.part(shellPlain, end)
+}
+
+func (l *shellLexer) endWord() {
+	if len(l.word) == 0 {
+		return
+	}
+	word := shellToken{
+		kind:  shellWord,
+		text:  l.line[l.start:l.pos],
+		parts: l.word,
+	}
+	l.tokens = append(l.tokens, word)
+	l.word = nil
+
+	if l.redirected && len(l.pending) > 0 {
+		l.redirected = false
+		doc := l.pending[len(l.pending)-1]
+		doc.delimiter = word.value()
+		l.pending[len(l.pending)-1] = doc
+	}
+}
+
+// quoted reads a quoted string starting at from, which is right after the
+// opening quote, or at the start of the line if the quote was left open by
+// the previous line.
+func (l *shellLexer) quoted(q byte, from int) {
+	i := from
+	for i < len(l.line) {
+		switch {
+		case l.line[i] == q:
+			if q == '"' {
+				l.doubleQuoted(i + 1)
+			} else {
+				l.part(shellSingleQuoted, i+1)
+			}
+			return
+		case q == '"' && l.line[i] == '\\':
+			i += 2
+			continue
+		}
+		i++
+	}
+
+	// unterminated, the string goes on in the next line.
+	l.quote = q
+	if q == '"' {
+		l.doubleQuoted(len(l.line))
+	} else {
+		l.part(shellSingleQuoted, len(l.line))
+	}
+}
+
+// doubleQuoted adds the double quoted string from the current position up to
+// end to the current word, splitting out the expansions in it.
+func (l *shellLexer) doubleQuoted(end int) {
+	for i := l.pos; i < end; i++ {
+		c := l.line[i]
+		if c == '\\' {
+			i++
+			continue
+		}
+		if c != '`' && (c != '$' || !isExpansionStart(l.line, i)) {
+			continue
+		}
+		if l.pos < i {
+			l.part(shellDoubleQuoted, i)
+		}
+		kind := shellSubstitution
+		var exp int
+		switch {
+		case c == '`':
+			exp = l.closing(i+1, '`')
+		case l.line[i+1] == '(':
+			exp = l.expansionEnd(i)
+		default:
+			kind, exp = shellVariable, l.expansionEnd(i)
+		}
+		l.part(kind, min(exp, end))
+		i = l.pos - 1
+	}
+	if l.pos < end {
+		l.part(shellDoubleQuoted, end)
+	}
+}
+
+// dollar reads a parameter expansion or a substitution.
+func (l *shellLexer) dollar() {
+	if !isExpansionStart(l.line, l.pos) {
+		l.plain(l.pos + 1)
+		return
+	}
+	kind := shellVariable
+	if l.pos+1 < len(l.line) && l.line[l.pos+1] == '(' {
+		kind = shellSubstitution
+	}
+	l.part(kind, l.expansionEnd(l.pos))
+}
+
+// expansionEnd returns the end of the expansion starting with a $ at i.
+func (l *shellLexer) expansionEnd(i int) int {
+	switch next := l.line[i+1]; {
+	case next == '(':
+		return l.closing(i+2, ')') //nolint:mnd
+	case next == '{':
+		return l.closing(i+2, '}') //nolint:mnd
+	case isNameChar(next) && !isDigit(next):
+		end := i + 1
+		for end < len(l.line) && isNameChar(l.line[end]) {
+			end++
+		}
+		return end
+	default:
+		// special parameters, e.g. $1, $@, $?.
+		return i + 2 //nolint:mnd
+	}
+}
+
+// closing returns the position right after the character closing a
+// construct that started right before from, taking nesting and quotes into
+// account, or the end of the line if it is not closed.
+func (l *shellLexer) closing(from int, closer byte) int {
+	var opener byte
+	switch closer {
+	case ')':
+		opener = '('
+	case '}':
+		opener = '{'
+	}
+	depth := 1
+	var quote byte
+	for i := from; i < len(l.line); i++ {
+		c := l.line[i]
+		switch {
+		case c == '\\' && quote != '\'':
+			i++
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == closer:
+			depth--
+			if depth == 0 {
+				return i + 1
+			}
+		case opener != 0 && c == opener:
+			depth++
+		case c == '\'' || c == '"':
+			quote = c
+		}
+	}
+	return len(l.line)
+}
+
+func (l *shellLexer) operator() {
+	l.endWord()
+	end := l.pos + 1
+	if end < len(l.line) {
+		switch l.line[l.pos : end+1] {
+		case "||", "&&", ";;", "|&":
+			end++
+		}
+	}
+	l.emit(shellOperator, l.line[l.pos:end])
+	l.pos = end
+}
+
+// redirect reads a redirection operator, including the file descriptor
+// number before it, if any.
+func (l *shellLexer) redirect() {
+	start := l.pos
+	if len(l.word) == 1 && l.word[0].kind == shellPlain && isNumber(l.word[0].text) {
+		start = l.start
+		l.word = nil
+	} else {
+		l.endWord()
+	}
+
+	end := l.pos
+	if l.line[end] == '&' {
+		end++
+	}
+	c := l.line[end]
+	end++
+	for end < len(l.line) && l.line[end] == c {
+		end++
+	}
+	if end < len(l.line) && (l.line[end] == '&' || l.line[end] == '|') {
+		end++
+	}
+	op := l.line[start:end]
+
+	if strings.HasSuffix(op, "<<") && !strings.HasSuffix(op, "<<<") {
+		stripTabs := end < len(l.line) && l.line[end] == '-'
+		if stripTabs {
+			end++
+			op += "-"
+		}
+		l.pending = append(l.pending, heredoc{stripTabs: stripTabs})
+		l.redirected = true
+	}
+
+	l.tokens = append(l.tokens, shellToken{kind: shellRedirect, text: op})
+	l.pos = end
+}
+
+// isExpansionStart reports whether the $ at i starts an expansion.
+func isExpansionStart(s string, i int) bool {
+	if s[i] == '`' {
+		return true
+	}
+	if i+1 >= len(s) {
+		return false
+	}
+	c := s[i+1]
+	return c == '(' || c == '{' || isNameChar(c) || strings.IndexByte("@*#?$!-", c) >= 0
+}
+
+func isNameChar(c byte) bool {
+	return c == '_' || isDigit(c) || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isNumber(s string) bool {
+	for i := range len(s) {
+		if !isDigit(s[i]) {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// assignment returns the length of the `NAME=` prefix of a word that is a
+// variable assignment, or 0 if it is not one.
+func assignment(word shellToken) int {
+	if len(word.parts) == 0 || word.parts[0].kind != shellPlain {
+		return 0
+	}
+	text := word.parts[0].text
+	i := strings.IndexByte(text, '=')
+	if i <= 0 || isDigit(text[0]) {
+		return 0
+	}
+	for j := range i {
+		if !isNameChar(text[j]) {
+			return 0
+		}
+	}
+	return i + 1
+}
@@ -0,0 +1,104 @@
+package fang
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestShellLexer(t *testing.T) {
+	type tok struct {
+		kind shellTokenKind
+		text string
+	}
+	tests := []struct {
+		name     string
+		lines    []string
+		expected [][]tok
+	}{
+		{
+			name:  "quoted pipe",
+			lines: []string{`echo "a | b" | app`},
+			expected: [][]tok{{
+				{shellWord, "echo"}, {shellSpace, " "}, {shellWord, `"a | b"`}, {shellSpace, " "},
+				{shellOperator, "|"}, {shellSpace, " "}, {shellWord, "app"},
+			}},
+		},
+		{
+			name:  "escaped quotes",
+			lines: []string{`app "say \"hi\"" it\'s`},
+			expected: [][]tok{{
+				{shellWord, "app"}, {shellSpace, " "}, {shellWord, `"say \"hi\""`}, {shellSpace, " "},
+				{shellWord, `it\'s`},
+			}},
+		},
+		{
+			name:  "substitution",
+			lines: []string{`app --at=$(date "+%s")`},
+			expected: [][]tok{{
+				{shellWord, "app"}, {shellSpace, " "}, {shellWord, `--at=$(date "+%s")`},
+			}},
+		},
+		{
+			name:  "separators and comments",
+			lines: []string{`FOO= app;app&&app # done`},
+			expected: [][]tok{{
+				{shellWord, "FOO="}, {shellSpace, " "}, {shellWord, "app"}, {shellOperator, ";"},
+				{shellWord, "app"}, {shellOperator, "&&"}, {shellWord, "app"}, {shellSpace, " "},
+				{shellComment, "# done"},
+			}},
+		},
+		{
+			name:  "redirects",
+			lines: []string{`app 2>&1 >>out.txt &>/dev/null`},
+			expected: [][]tok{{
+				{shellWord, "app"}, {shellSpace, " "}, {shellRedirect, "2>&"}, {shellWord, "1"},
+				{shellSpace, " "}, {shellRedirect, ">>"}, {shellWord, "out.txt"}, {shellSpace, " "},
+				{shellRedirect, "&>"}, {shellWord, "/dev/null"},
+			}},
+		},
+		{
+			name:  "heredoc",
+			lines: []string{`app <<-'EOF' | cat`, `hello $name`, `EOF`, `app`},
+			expected: [][]tok{
+				{
+					{shellWord, "app"}, {shellSpace, " "}, {shellRedirect, "<<-"}, {shellWord, "'EOF'"},
+					{shellSpace, " "}, {shellOperator, "|"}, {shellSpace, " "}, {shellWord, "cat"},
+				},
+				{{shellHeredoc, "hello $name"}},
+				{{shellHeredocEnd, "EOF"}},
+				{{shellWord, "app"}},
+			},
+		},
+		{
+			name:  "multi-line quote and continuation",
+			lines: []string{`app "a`, `b" \`, `c`},
+			expected: [][]tok{
+				{{shellWord, "app"}, {shellSpace, " "}, {shellWord, `"a`}},
+				{{shellWord, `b"`}, {shellSpace, " "}, {shellContinuation, `\`}},
+				{{shellWord, "c"}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var lexer shellLexer
+			for i, line := range tt.lines {
+				var got []tok
+				for _, token := range lexer.lex(line) {
+					got = append(got, tok{token.kind, token.text})
+				}
+				require.Equal(t, tt.expected[i], got, line)
+			}
+		})
+	}
+}
+
+func TestShellWordValue(t *testing.T) {
+	var lexer shellLexer
+	tokens := lexer.lex(`'a b'"c \"d\""e\ f$HOME`)
+	require.Len(t, tokens, 1)
+	require.Equal(t, `a bc "d"e f$HOME`, tokens[0].value())
+	require.True(t, tokens[0].quoted())
+}
@@ -0,0 +1,339 @@
+package fang
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// Spec is a stable description of the interface of a command and its
+// subcommands, meant to be committed and compared against with [DiffSpec].
+//
+// Hidden commands and flags are not part of the spec.
+type Spec struct {
+	Name     string     `json:"name"`
+	Aliases  []string   `json:"aliases,omitempty"`
+	Args     []ArgSpec  `json:"args,omitempty"`
+	Flags    []FlagSpec `json:"flags,omitempty"`
+	Commands []Spec     `json:"commands,omitempty"`
+}
+
+// ArgSpec describes a positional argument, as declared in the `Use` line of a
+// command, e.g. `<name>` or `[files...]`.
+type ArgSpec struct {
+	Name     string `json:"name"`
+	Required bool   `json:"required,omitempty"`
+	Variadic bool   `json:"variadic,omitempty"`
+}
+
+// FlagSpec describes a flag defined by a command.
+type FlagSpec struct {
+	Name       string `json:"name"`
+	Shorthand  string `json:"shorthand,omitempty"`
+	Type       string `json:"type"`
+	Default    string `json:"default,omitempty"`
+	Required   bool   `json:"required,omitempty"`
+	Persistent bool   `json:"persistent,omitempty"`
+}
+
+// NewSpec returns the spec of the given command tree.
+//
+// Example:
+//
+//	func TestSpec(t *testing.T) {
+//		bts, err := os.ReadFile("testdata/spec.json")
+//		if err != nil {
+//			t.Fatal(err)
+//		}
+//		var spec fang.Spec
+//		if err := json.Unmarshal(bts, &spec); err != nil {
+//			t.Fatal(err)
+//		}
+//		for _, change := range fang.DiffSpec(spec, fang.NewSpec(newRootCmd())) {
+//			if change.Breaking {
+//				t.Error(change)
+//			}
+//		}
+//	}
+func NewSpec(root *cobra.Command) Spec {
+	spec := Spec{
+		Name:    root.Name(),
+		Aliases: root.Aliases,
+		Args:    useArgs(root),
+	}
+	persistent := root.PersistentFlags()
+	visitOwnFlags(root, func(f *pflag.Flag) {
+		if f.Hidden {
+			return
+		}
+		def := f.DefValue
+		if isSensitive(f) {
+			def = ""
+		}
+		spec.Flags = append(spec.Flags, FlagSpec{
+			Name:       f.Name,
+			Shorthand:  f.Shorthand,
+			Type:       f.Value.Type(),
+			Default:    def,
+			Required:   isRequired(f),
+			Persistent: persistent.Lookup(f.Name) == f,
+		})
+	})
+	for _, sc := range root.Commands() {
+		if sc.Hidden {
+			continue
+		}
+		spec.Commands = append(spec.Commands, NewSpec(sc))
+	}
+	return spec
+}
+
+// useArgs returns the positional arguments declared in the `Use` line of the
+// given command.
+func useArgs(c *cobra.Command) []ArgSpec {
+	fields := strings.Fields(c.Use)
+	if len(fields) == 0 {
+		return nil
+	}
+	var args []ArgSpec
+	for _, field := range fields[1:] {
+		arg := ArgSpec{Required: true}
+		field, arg.Variadic = strings.CutSuffix(field, "...")
+		switch {
+		case strings.HasPrefix(field, "["):
+			arg.Required = false
+			field = strings.TrimSuffix(strings.TrimPrefix(field, "["), "]")
+		case strings.HasPrefix(field, "<"):
+			field = strings.TrimSuffix(strings.TrimPrefix(field, "<"), ">")
+		}
+		field, variadic := strings.CutSuffix(field, "...")
+		arg.Variadic = arg.Variadic || variadic
+		arg.Name = field
+		if arg.Name == "" || arg.Name == "flags" || arg.Name == "command" || strings.HasPrefix(arg.Name, "-") {
+			continue
+		}
+		args = append(args, arg)
+	}
+	return args
+}
+
+// SpecChange is a difference between two specs.
+type SpecChange struct {
+	Command  string // the path of the command, e.g. "app sub".
+	Message  string
+	Breaking bool // whether existing invocations may stop working.
+}
+
+// String implements [fmt.Stringer].
+func (c SpecChange) String() string {
+	s := c.Command + ": " + c.Message
+	if c.Breaking {
+		s += " (breaking)"
+	}
+	return s
+}
+
+// DiffSpec compares two specs, reporting removed commands, aliases and flags,
+// renamed flags, changed flag types, defaults and shorthands, and new required
+// flags and arguments as breaking changes. Additions are reported as
+// non-breaking changes.
+func DiffSpec(before, after Spec) []SpecChange {
+	var d specDiff
+	d.command(before.Name, before, after)
+	return d.changes
+}
+
+type specDiff struct {
+	changes []SpecChange
+}
+
+func (d *specDiff) add(path string, breaking bool, format string, args ...any) {
+	d.changes = append(d.changes, SpecChange{
+		Command:  path,
+		Message:  fmt.Sprintf(format, args...),
+		Breaking: breaking,
+	})
+}
+
+func (d *specDiff) command(path string, before, after Spec) {
+	for _, alias := range before.Aliases {
+		if !slices.Contains(after.Aliases, alias) && alias != after.Name {
+			d.add(path, true, "alias %q removed", alias)
+		}
+	}
+	for _, alias := range after.Aliases {
+		if !slices.Contains(before.Aliases, alias) {
+			d.add(path, false, "alias %q added", alias)
+		}
+	}
+
+	d.args(path, before.Args, after.Args)
+	d.flags(path, before.Flags, after.Flags)
+
+	for _, oc := range before.Commands {
+		i := slices.IndexFunc(after.Commands, func(nc Spec) bool {
+			return nc.Name == oc.Name
+		})
+		if i < 0 {
+			// still reachable through an alias.
+			i = slices.IndexFunc(after.Commands, func(nc Spec) bool {
+				return slices.Contains(nc.Aliases, oc.Name)
+			})
+		}
+		if i < 0 {
+			d.add(path, true, "command %q removed", oc.Name)
+			continue
+		}
+		nc := after.Commands[i]
+		if nc.Name != oc.Name {
+			d.add(path, false, "command %q renamed to %q", oc.Name, nc.Name)
+		}
+		d.command(path+" "+oc.Name, oc, nc)
+	}
+	for _, nc := range after.Commands {
+		if !slices.ContainsFunc(before.Commands, func(oc Spec) bool {
+			return oc.Name == nc.Name || slices.Contains(nc.Aliases, oc.Name)
+		}) {
+			d.add(path, false, "command %q added", nc.Name)
+		}
+	}
+}
+
+func (d *specDiff) args(path string, before, after []ArgSpec) {
+	for i, na := range after {
+		if i >= len(before) {
+			d.add(path, na.Required, "argument %q added", na.Name)
+			continue
+		}
+		oa := before[i]
+		if na.Required && !oa.Required {
+			d.add(path, true, "argument %q is now required", na.Name)
+		}
+		if oa.Variadic && !na.Variadic {
+			d.add(path, true, "argument %q no longer accepts multiple values", na.Name)
+		}
+	}
+	for _, oa := range before[min(len(before), len(after)):] {
+		d.add(path, true, "argument %q removed", oa.Name)
+	}
+}
+
+func (d *specDiff) flags(path string, before, after []FlagSpec) {
+	find := func(flags []FlagSpec, name string) (FlagSpec, bool) {
+		i := slices.IndexFunc(flags, func(f FlagSpec) bool { return f.Name == name })
+		if i < 0 {
+			return FlagSpec{}, false
+		}
+		return flags[i], true
+	}
+
+	var removed, added []FlagSpec
+	for _, of := range before {
+		if _, ok := find(after, of.Name); !ok {
+			removed = append(removed, of)
+		}
+	}
+	for _, nf := range after {
+		if _, ok := find(before, nf.Name); !ok {
+			added = append(added, nf)
+		}
+	}
+
+	for _, of := range before {
+		nf, ok := find(after, of.Name)
+		if !ok {
+			// a flag with the same shorthand and type, or the only one with
+			// the same type, was most likely renamed.
+			i := slices.IndexFunc(added, func(nf FlagSpec) bool {
+				return nf.Type == of.Type && (of.Shorthand != "" && nf.Shorthand == of.Shorthand ||
+					len(removed) == 1 && len(added) == 1)
+			})
+			if i < 0 {
+				d.add(path, true, "flag --%s removed", of.Name)
+				continue
+			}
+			nf = added[i]
+			added = slices.Delete(added, i, i+1)
+			d.add(path, true, "flag --%s renamed to --%s", of.Name, nf.Name)
+		}
+		d.flag(path, of, nf)
+	}
+	for _, nf := range added {
+		if nf.Required {
+			d.add(path, true, "required flag --%s added", nf.Name)
+			continue
+		}
+		d.add(path, false, "flag --%s added", nf.Name)
+	}
+}
+
+func (d *specDiff) flag(path string, before, after FlagSpec) {
+	if before.Type != after.Type {
+		d.add(path, true, "flag --%s type changed from %s to %s", after.Name, before.Type, after.Type)
+	}
+	if before.Default != after.Default {
+		d.add(path, true, "flag --%s default changed from %q to %q", after.Name, before.Default, after.Default)
+	}
+	switch {
+	case before.Shorthand == after.Shorthand:
+	case before.Shorthand == "":
+		d.add(path, false, "flag --%s shorthand -%s added", after.Name, after.Shorthand)
+	case after.Shorthand == "":
+		d.add(path, true, "flag --%s shorthand -%s removed", after.Name, before.Shorthand)
+	default:
+		d.add(path, true, "flag --%s shorthand changed from -%s to -%s", after.Name, before.Shorthand, after.Shorthand)
+	}
+	if after.Required && !before.Required {
+		d.add(path, true, "flag --%s is now required", after.Name)
+	}
+	if before.Persistent && !after.Persistent {
+		d.add(path, true, "flag --%s is no longer persistent", after.Name)
+	}
+}
+
+// flagValueName returns the name of the value of the given flag, e.g. "file"
+// for "--config `file`", and its usage without the back quotes. Boolean and
+// count flags have no value name.
+func flagValueName(f *pflag.Flag) (string, string) {
+	name, usage := pflag.UnquoteUsage(f)
+	if f.NoOptDefVal != "" {
+		return "", usage
+	}
+	if name == "" {
+		name = "value"
+	}
+	return name, usage
+}
+
+// flagNames returns the names of the given flag, e.g. "-o" and "--output".
+func flagNames(f *pflag.Flag) []string {
+	if f.Shorthand == "" {
+		return []string{"--" + f.Name}
+	}
+	return []string{"-" + f.Shorthand, "--" + f.Name}
+}
+
+// isRepeatable reports whether the given flag can be given more than once.
+func isRepeatable(f *pflag.Flag) bool {
+	switch typ := f.Value.Type(); {
+	case typ == "count", strings.HasSuffix(typ, "Slice"), strings.HasSuffix(typ, "Array"):
+		return true
+	}
+	return false
+}
+
+// argString returns the given argument as written in a usage line, e.g.
+// "<id>" or "[files]...".
+func argString(arg ArgSpec) string {
+	s := fmt.Sprintf("[%s]", arg.Name)
+	if arg.Required {
+		s = fmt.Sprintf("<%s>", arg.Name)
+	}
+	if arg.Variadic {
+		s += "..."
+	}
+	return s
+}
@@ -0,0 +1,115 @@
+package fang_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"charm.land/fang/v2"
+	"github.com/charmbracelet/x/exp/golden"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/require"
+)
+
+func specRoot(modify func(root, get *cobra.Command)) *cobra.Command {
+	root := &cobra.Command{Use: "app"}
+	root.PersistentFlags().BoolP("verbose", "v", false, "Verbose output")
+	get := &cobra.Command{
+		Use:     "get <id> [fields...] [flags]",
+		Aliases: []string{"g"},
+		Run:     func(*cobra.Command, []string) {},
+	}
+	get.Flags().StringP("output", "o", "text", "Output format")
+	get.Flags().Int("limit", 10, "Maximum number of items")
+	get.Flags().String("token", "", "Token to use")
+	_ = get.Flags().MarkHidden("token")
+	root.AddCommand(
+		get,
+		&cobra.Command{Use: "delete <id>", Run: func(*cobra.Command, []string) {}},
+		&cobra.Command{Use: "debug", Hidden: true},
+	)
+	if modify != nil {
+		modify(root, get)
+	}
+	return root
+}
+
+func TestSpec(t *testing.T) {
+	bts, err := json.MarshalIndent(fang.NewSpec(specRoot(nil)), "", "  ")
+	require.NoError(t, err)
+	golden.RequireEqual(t, bts)
+
+	var spec fang.Spec
+	require.NoError(t, json.Unmarshal(bts, &spec))
+	require.Empty(t, fang.DiffSpec(spec, fang.NewSpec(specRoot(nil))))
+}
+
+func TestDiffSpec(t *testing.T) {
+	before := fang.NewSpec(specRoot(nil))
+	after := fang.NewSpec(specRoot(func(root, get *cobra.Command) {
+		del, _, _ := root.Find([]string{"delete"})
+		root.RemoveCommand(del)
+		root.AddCommand(&cobra.Command{Use: "list", Run: func(*cobra.Command, []string) {}})
+		get.Use = "get <id> <field>"
+		get.Aliases = []string{"fetch"}
+		get.ResetFlags()
+		get.Flags().StringP("format", "o", "text", "Output format")
+		get.Flags().Int("limit", 20, "Maximum number of items")
+		get.Flags().String("since", "", "Only items since")
+		_ = get.MarkFlagRequired("since")
+	}))
+
+	var changes []string
+	for _, change := range fang.DiffSpec(before, after) {
+		changes = append(changes, change.String())
+	}
+	require.Equal(t, []string{
+		`app: command "delete" removed (breaking)`,
+		`app get: alias "g" removed (breaking)`,
+		`app get: alias "fetch" added`,
+		`app get: argument "field" is now required (breaking)`,
+		`app get: argument "field" no longer accepts multiple values (breaking)`,
+		`app get: flag --limit default changed from "10" to "20" (breaking)`,
+		`app get: flag --output renamed to --format (breaking)`,
+		`app get: required flag --since added (breaking)`,
+		`app: command "list" added`,
+	}, changes)
+}
+
+func generatorRoot() *cobra.Command {
+	root := specRoot(nil)
+	root.Short = "A sample app"
+	root.Version = "1.0.0"
+	root.PersistentFlags().CountP("quiet", "q", "Less output, can be repeated")
+	get, _, _ := root.Find([]string{"get"})
+	get.Short = "Get an item"
+	get.Long = "Get an item by its \"id\", printing the given fields."
+	get.Example = `
+# Get an item as JSON:
+app get 10 --output json
+	`
+	get.ValidArgs = []string{"10\tthe first item", "20"}
+	get.Flags().StringSlice("label", nil, "Filter by `label`")
+	_ = fang.MarkFlagEnum(get.Flags(), "output", "text", "json")
+	_ = get.MarkFlagRequired("limit")
+	cfg := &cobra.Command{Use: "config", Short: "Manage the config"}
+	cfg.AddCommand(&cobra.Command{
+		Use:   "set <key> <value>",
+		Short: "Set a key",
+		Run:   func(*cobra.Command, []string) {},
+	})
+	root.AddCommand(cfg)
+	return root
+}
+
+func TestWriteUsageSpec(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, fang.WriteUsageSpec(&buf, generatorRoot()))
+	golden.RequireEqual(t, buf.Bytes())
+}
+
+func TestWriteFigSpec(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, fang.WriteFigSpec(&buf, generatorRoot()))
+	golden.RequireEqual(t, buf.Bytes())
+}
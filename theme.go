@@ -26,6 +26,8 @@ type ColorScheme struct {
 	FlagDefault    color.Color
 	Command        color.Color
 	QuotedString   color.Color
+	Variable       color.Color // variables and command substitutions in examples
+	Operator       color.Color // operators like |, && and ; in examples
 	Argument       color.Color
 	Help           color.Color
 	Dash           color.Color
@@ -55,10 +57,15 @@ func DefaultColorScheme(c lipgloss.LightDarkFunc) ColorScheme {
 		Description:    c(charmtone.Charcoal, charmtone.Ash), // flag and command descriptions
 		FlagDefault:    c(charmtone.Smoke, charmtone.Squid),  // flag default values in descriptions
 		QuotedString:   c(charmtone.Coral, charmtone.Salmon),
+		Variable:       c(charmtone.Cumin, charmtone.Tang),
+		Operator:       c(charmtone.Zinc, charmtone.Lichen),
+		Help:           c(charmtone.Charcoal, charmtone.Ash),
+		Dash:           c(charmtone.Squid, charmtone.Oyster),
 		ErrorHeader: [2]color.Color{
 			charmtone.Butter,
 			charmtone.Cherry,
 		},
+		ErrorDetails: c(charmtone.Cherry, charmtone.Coral),
 	}
 }
 
@@ -74,6 +81,8 @@ func AnsiColorScheme(c lipgloss.LightDarkFunc) ColorScheme {
 		FlagDefault:  lipgloss.BrightMagenta,
 		Command:      lipgloss.Cyan,
 		QuotedString: lipgloss.Green,
+		Variable:     lipgloss.Yellow,
+		Operator:     lipgloss.Blue,
 		Argument:     base,
 		Help:         base,
 		Dash:         base,
@@ -82,6 +91,89 @@ func AnsiColorScheme(c lipgloss.LightDarkFunc) ColorScheme {
 	}
 }
 
+// Palette is a small set of semantic colors from which a complete
+// [ColorScheme] can be derived with [NewColorScheme].
+//
+// Any color left nil falls back to the [DefaultColorScheme].
+type Palette struct {
+	Primary   color.Color // titles and program names
+	Secondary color.Color // commands and flags
+	Muted     color.Color // comments, dimmed arguments and flag defaults
+	Error     color.Color // error header and details
+}
+
+// Minimum contrast ratios used when deriving a colorscheme from a [Palette].
+const (
+	paletteContrast      = 4.5
+	paletteMutedContrast = 3
+)
+
+// NewColorScheme derives a complete colorscheme from the given palette.
+//
+// Colors are adjusted where needed so they remain readable against the
+// background they are rendered on, and every [ColorScheme] field is set.
+//
+// Example:
+//
+//	fang.WithColorSchemeFunc(func(c lipgloss.LightDarkFunc) fang.ColorScheme {
+//		return fang.NewColorScheme(c, fang.Palette{
+//			Primary:   c(lipgloss.Color("#FF6B6B"), lipgloss.Color("#4ECDC4")),
+//			Secondary: c(lipgloss.Color("#95E1D3"), lipgloss.Color("#F38181")),
+//			Muted:     c(lipgloss.Color("#999999"), lipgloss.Color("#666666")),
+//		})
+//	})
+func NewColorScheme(c lipgloss.LightDarkFunc, p Palette) ColorScheme {
+	def := DefaultColorScheme(c)
+	primary := cmpColor(p.Primary, def.Title)
+	secondary := cmpColor(p.Secondary, def.Command)
+	muted := cmpColor(p.Muted, def.Comment)
+	errColor := cmpColor(p.Error, def.ErrorHeader[1])
+
+	// we don't know the actual terminal background, so assume it is
+	// either black or white.
+	background := c(lipgloss.Color("#FFFFFF"), lipgloss.Color("#000000"))
+	codeblock := def.Codeblock
+	readable := func(fg color.Color, ratio float64) color.Color {
+		return ensureContrast(fg, ratio, background, codeblock)
+	}
+
+	errorHeaderFg := lipgloss.Color("#FFFFFF")
+	if contrastRatio(errorHeaderFg, errColor) < contrastRatio(lipgloss.Color("#000000"), errColor) {
+		errorHeaderFg = lipgloss.Color("#000000")
+	}
+
+	return ColorScheme{
+		Base:           def.Base,
+		Title:          readable(primary, paletteContrast),
+		Description:    def.Description,
+		Codeblock:      codeblock,
+		Program:        readable(primary, paletteContrast),
+		DimmedArgument: readable(muted, paletteMutedContrast),
+		Comment:        readable(muted, paletteMutedContrast),
+		Flag:           readable(secondary, paletteContrast),
+		FlagDefault:    readable(muted, paletteMutedContrast),
+		Command:        readable(secondary, paletteContrast),
+		QuotedString:   readable(lipgloss.Complementary(secondary), paletteContrast),
+		Variable:       readable(lipgloss.Complementary(primary), paletteContrast),
+		Operator:       readable(primary, paletteContrast),
+		Argument:       def.Argument,
+		Help:           def.Help,
+		Dash:           readable(muted, paletteMutedContrast),
+		ErrorHeader:    [2]color.Color{errorHeaderFg, errColor},
+		ErrorDetails:   readable(errColor, paletteContrast),
+	}
+}
+
+// cmpColor returns the first of the given colors that is an actual color.
+func cmpColor(colors ...color.Color) color.Color {
+	for _, c := range colors {
+		if isColor(c) {
+			return c
+		}
+	}
+	return lipgloss.NoColor{}
+}
+
 // Styles represents all the styles used.
 type Styles struct {
 	Text            lipgloss.Style
@@ -89,10 +181,42 @@ type Styles struct {
 	Span            lipgloss.Style
 	ErrorHeader     lipgloss.Style
 	ErrorText       lipgloss.Style
+	ErrorDetails    lipgloss.Style
+	ErrorHint       lipgloss.Style
 	FlagDescription lipgloss.Style
 	FlagDefault     lipgloss.Style
+	Link            lipgloss.Style
+	Badge           lipgloss.Style
 	Codeblock       Codeblock
 	Program         Program
+
+	// accessible renders plain, linear text instead, see [WithAccessible].
+	accessible bool
+	// hyperlinks renders links as OSC 8 hyperlinks.
+	hyperlinks bool
+	// width overrides the width of the terminal, if set.
+	width int
+	// redact redacts secrets from errors, if set.
+	redact func(string) string
+}
+
+// maxWidth returns the width to render to.
+func (s Styles) maxWidth() int {
+	if s.width > 0 {
+		return s.width
+	}
+	return width()
+}
+
+// Redact returns the given text with the secrets fang knows about redacted:
+// the values of the flags marked with [MarkFlagSensitive], and the matches of
+// the patterns set with [WithRedactPatterns]. Custom [ErrorHandler]s should
+// use it on the errors they print.
+func (s Styles) Redact(text string) string {
+	if s.redact == nil {
+		return text
+	}
+	return s.redact(text)
 }
 
 // Codeblock styles.
@@ -111,6 +235,8 @@ type Program struct {
 	Argument       lipgloss.Style
 	DimmedArgument lipgloss.Style
 	QuotedString   lipgloss.Style
+	Variable       lipgloss.Style
+	Operator       lipgloss.Style
 }
 
 func mustColorscheme(cs func(lipgloss.LightDarkFunc) ColorScheme) ColorScheme {
@@ -136,6 +262,9 @@ func makeStyles(cs ColorScheme) Styles {
 			Transform(titleFirstWord),
 		FlagDefault: lipgloss.NewStyle().
 			Foreground(cs.FlagDefault),
+		Link: lipgloss.NewStyle().
+			Foreground(cs.Help).
+			Underline(true),
 		Codeblock: Codeblock{
 			Base: lipgloss.NewStyle().
 				Background(cs.Codeblock).
@@ -166,6 +295,12 @@ func makeStyles(cs ColorScheme) Styles {
 				QuotedString: lipgloss.NewStyle().
 					Background(cs.Codeblock).
 					Foreground(cs.QuotedString),
+				Variable: lipgloss.NewStyle().
+					Background(cs.Codeblock).
+					Foreground(cs.Variable),
+				Operator: lipgloss.NewStyle().
+					Background(cs.Codeblock).
+					Foreground(cs.Operator),
 			},
 		},
 		Program: Program{
@@ -181,6 +316,10 @@ func makeStyles(cs ColorScheme) Styles {
 				Foreground(cs.Command),
 			QuotedString: lipgloss.NewStyle().
 				Foreground(cs.QuotedString),
+			Variable: lipgloss.NewStyle().
+				Foreground(cs.Variable),
+			Operator: lipgloss.NewStyle().
+				Foreground(cs.Operator),
 		},
 		Span: lipgloss.NewStyle().
 			Background(cs.Codeblock),
@@ -188,6 +327,15 @@ func makeStyles(cs ColorScheme) Styles {
 			MarginLeft(2).
 			Width(width() - 4).
 			Transform(titleFirstWord),
+		ErrorDetails: lipgloss.NewStyle().
+			Foreground(cs.ErrorDetails).
+			MarginLeft(2).
+			Width(width() - 4),
+		ErrorHint: lipgloss.NewStyle().
+			Foreground(cs.Base).
+			Bold(true).
+			MarginLeft(2).
+			Width(width() - 4),
 		ErrorHeader: lipgloss.NewStyle().
 			Foreground(cs.ErrorHeader[0]).
 			Background(cs.ErrorHeader[1]).
@@ -196,6 +344,11 @@ func makeStyles(cs ColorScheme) Styles {
 			Margin(1).
 			MarginLeft(2).
 			SetString("ERROR"),
+		Badge: lipgloss.NewStyle().
+			Foreground(cs.ErrorHeader[0]).
+			Background(cs.ErrorHeader[1]).
+			Bold(true).
+			Padding(0, 1),
 	}
 }
 
@@ -1,8 +1,11 @@
 package fang
 
 import (
+	"image/color"
+	"reflect"
 	"testing"
 
+	"charm.land/lipgloss/v2"
 	"github.com/stretchr/testify/require"
 )
 
@@ -129,3 +132,38 @@ func TestTitleFirstWord(t *testing.T) {
 		})
 	}
 }
+
+func TestNewColorScheme(t *testing.T) {
+	for name, isDark := range map[string]bool{"light": false, "dark": true} {
+		t.Run(name, func(t *testing.T) {
+			c := lipgloss.LightDark(isDark)
+			cs := NewColorScheme(c, Palette{
+				Primary:   c(lipgloss.Color("#FFFF00"), lipgloss.Color("#000080")),
+				Secondary: lipgloss.Color("#777777"),
+			})
+
+			v := reflect.ValueOf(cs)
+			for i := range v.NumField() {
+				field := v.Type().Field(i)
+				if field.Name == "ErrorHeader" {
+					require.True(t, isColor(cs.ErrorHeader[0]), "ErrorHeader[0]")
+					require.True(t, isColor(cs.ErrorHeader[1]), "ErrorHeader[1]")
+					continue
+				}
+				col, _ := v.Field(i).Interface().(color.Color)
+				require.True(t, isColor(col), field.Name)
+			}
+
+			background := c(lipgloss.Color("#FFFFFF"), lipgloss.Color("#000000"))
+			for name, fg := range map[string]color.Color{
+				"Title":   cs.Title,
+				"Program": cs.Program,
+				"Flag":    cs.Flag,
+				"Command": cs.Command,
+			} {
+				require.GreaterOrEqual(t, contrastRatio(fg, background), paletteContrast, name)
+				require.GreaterOrEqual(t, contrastRatio(fg, cs.Codeblock), paletteContrast, name)
+			}
+		})
+	}
+}
@@ -0,0 +1,326 @@
+package fang
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"charm.land/lipgloss/v2"
+	"github.com/charmbracelet/colorprofile"
+	"github.com/spf13/cobra"
+)
+
+const (
+	// themeFile is the file the theme is saved to when config files aren't
+	// enabled, see [WithConfig].
+	themeFile = "theme"
+	// themeConfigKey is the key the theme is saved at in the config file
+	// otherwise.
+	themeConfigKey = "theme"
+	// themeCmdAnnotation marks the `theme` command.
+	themeCmdAnnotation = "fang_theme_cmd"
+)
+
+type namedColorScheme struct {
+	name string
+	fn   ColorSchemeFunc
+}
+
+// colorSchemes returns the built-in colorschemes followed by the ones
+// registered with [WithNamedColorScheme].
+func (s settings) colorSchemes() []namedColorScheme {
+	schemes := []namedColorScheme{
+		{"default", DefaultColorScheme},
+		{"ansi", AnsiColorScheme},
+	}
+	for _, cs := range s.namedColorSchemes {
+		if i := findColorScheme(schemes, cs.name); i >= 0 {
+			schemes[i] = cs
+			continue
+		}
+		schemes = append(schemes, cs)
+	}
+	return schemes
+}
+
+// colorSchemeFor returns the colorscheme persisted with `theme set` for the
+// given command, see [savedTheme], or the one set with the options otherwise.
+// The command's flags should be parsed, so --config is known.
+func (s settings) colorSchemeFor(c *cobra.Command) ColorSchemeFunc {
+	if !s.themeCommand {
+		return s.colorscheme
+	}
+	schemes := s.colorSchemes()
+	if i := findColorScheme(schemes, savedTheme(c, s.config)); i >= 0 {
+		return schemes[i].fn
+	}
+	return s.colorscheme
+}
+
+func findColorScheme(schemes []namedColorScheme, name string) int {
+	for i, cs := range schemes {
+		if strings.EqualFold(cs.name, name) {
+			return i
+		}
+	}
+	return -1
+}
+
+// configDir returns the directory where the configuration of the given
+// program is stored.
+func configDir(root *cobra.Command) (string, error) {
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		var err error
+		dir, err = os.UserConfigDir()
+		if err != nil {
+			return "", fmt.Errorf("could not find config directory: %w", err)
+		}
+	}
+	return filepath.Join(dir, root.Name()), nil
+}
+
+// isThemeKey reports whether the given config key is the one the theme is
+// saved at, which is the case if the tree has the `theme` command.
+func isThemeKey(root *cobra.Command, key string) bool {
+	if key != themeConfigKey {
+		return false
+	}
+	sc := findSubCommand(root, "theme")
+	return sc != nil && sc.Annotations[themeCmdAnnotation] != ""
+}
+
+// savedTheme returns the name of the theme persisted with `theme set`, if
+// any: the theme key of the config file if config is set, or the theme file.
+func savedTheme(c *cobra.Command, config bool) string {
+	if config {
+		if path, _ := findConfig(c); path != "" {
+			cfg, err := loadConfig(path)
+			if name, ok := cfg[themeConfigKey].(string); err == nil && ok {
+				return name
+			}
+		}
+	}
+	dir, err := configDir(c.Root())
+	if err != nil {
+		return ""
+	}
+	bts, err := os.ReadFile(filepath.Join(dir, themeFile))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(bts))
+}
+
+// saveTheme persists the given theme in the config file if config is set,
+// or in the theme file otherwise, and returns the path of the file.
+func saveTheme(c *cobra.Command, name string, config bool) (string, error) {
+	dir, err := configDir(c.Root())
+	if err != nil {
+		return "", err
+	}
+	if config {
+		path, err := configFileFor(c)
+		if err != nil {
+			return "", err
+		}
+		cfg, err := readConfig(path)
+		if err != nil {
+			return "", err
+		}
+		setConfig(cfg, themeConfigKey, name)
+		if err := saveConfig(path, cfg); err != nil {
+			return "", err
+		}
+		return path, removeThemeFile(dir)
+	}
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return "", fmt.Errorf("could not create config directory: %w", err)
+	}
+	path := filepath.Join(dir, themeFile)
+	if err := os.WriteFile(path, []byte(name+"\n"), 0o600); err != nil {
+		return "", fmt.Errorf("could not save theme: %w", err)
+	}
+	return path, nil
+}
+
+// resetTheme removes the persisted theme, from the config file if config is
+// set, and from the theme file.
+func resetTheme(c *cobra.Command, config bool) error {
+	dir, err := configDir(c.Root())
+	if err != nil {
+		return err
+	}
+	if config {
+		path, err := configFileFor(c)
+		if err != nil {
+			return err
+		}
+		cfg, err := readConfig(path)
+		if err != nil {
+			return err
+		}
+		if unsetConfig(cfg, themeConfigKey) {
+			if err := saveConfig(path, cfg); err != nil {
+				return err
+			}
+		}
+	}
+	return removeThemeFile(dir)
+}
+
+func removeThemeFile(dir string) error {
+	err := os.Remove(filepath.Join(dir, themeFile))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("could not reset theme: %w", err)
+	}
+	return nil
+}
+
+func themeCmd(opts settings) *cobra.Command {
+	schemes := opts.colorSchemes()
+	names := make([]string, 0, len(schemes))
+	for _, cs := range schemes {
+		names = append(names, cs.name)
+	}
+
+	stylesFor := func(c *cobra.Command) Styles {
+		return opts.styles(mustColorscheme(opts.colorSchemeFor(c)))
+	}
+
+	cmd := &cobra.Command{
+		Use:                   "theme",
+		Short:                 "List the available themes",
+		SilenceUsage:          true,
+		DisableFlagsInUseLine: true,
+		Hidden:                true,
+		Args:                  cobra.NoArgs,
+		Annotations:           map[string]string{themeCmdAnnotation: "true"},
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			w := colorprofile.NewWriter(cmd.OutOrStdout(), os.Environ())
+			styles := stylesFor(cmd)
+			current := savedTheme(cmd, opts.config)
+			space := calculateSpace(names, nil)
+			renderGroup(w, styles, space, "themes", func(yield func(string, string) bool) {
+				for _, name := range names {
+					var help string
+					if strings.EqualFold(name, current) {
+						help = styles.FlagDefault.Render("(current)")
+					}
+					if !yield(styles.Program.Command.Render(name), help) {
+						return
+					}
+				}
+			})
+			_, _ = fmt.Fprintln(w)
+			return nil
+		},
+	}
+
+	cmd.AddCommand(
+		&cobra.Command{
+			Use:                   "preview [theme]",
+			Short:                 "Preview the available themes",
+			DisableFlagsInUseLine: true,
+			Args:                  cobra.MaximumNArgs(1),
+			ValidArgs:             names,
+			RunE: func(cmd *cobra.Command, args []string) error {
+				if len(args) > 0 && findColorScheme(schemes, args[0]) < 0 {
+					return fmt.Errorf("unknown theme %q", args[0])
+				}
+				w := colorprofile.NewWriter(cmd.OutOrStdout(), os.Environ())
+				styles := stylesFor(cmd)
+				for _, cs := range schemes {
+					if len(args) > 0 && !strings.EqualFold(args[0], cs.name) {
+						continue
+					}
+					_, _ = fmt.Fprintln(w, styles.Title.Render(cs.name))
+					_, _ = fmt.Fprintln(w, previewColorScheme(opts, cs.fn, w.Profile))
+				}
+				return nil
+			},
+		},
+		&cobra.Command{
+			Use:                   "set <theme>",
+			Short:                 "Set the theme to use",
+			DisableFlagsInUseLine: true,
+			Args:                  cobra.ExactArgs(1),
+			ValidArgs:             names,
+			RunE: func(cmd *cobra.Command, args []string) error {
+				i := findColorScheme(schemes, args[0])
+				if i < 0 {
+					return fmt.Errorf("unknown theme %q", args[0])
+				}
+				path, err := saveTheme(cmd, schemes[i].name, opts.config)
+				if err != nil {
+					return err
+				}
+				cmd.Printf("Theme set to %s in %s\n", schemes[i].name, path)
+				return nil
+			},
+		},
+		&cobra.Command{
+			Use:                   "reset",
+			Short:                 "Go back to the default theme",
+			DisableFlagsInUseLine: true,
+			Args:                  cobra.NoArgs,
+			RunE: func(cmd *cobra.Command, _ []string) error {
+				if err := resetTheme(cmd, opts.config); err != nil {
+					return err
+				}
+				cmd.Println("Theme reset")
+				return nil
+			},
+		},
+	)
+	return cmd
+}
+
+// previewColorScheme renders a sample help page and error using both the
+// light and the dark variants of the given colorscheme, side by side if they
+// fit.
+func previewColorScheme(opts settings, cs ColorSchemeFunc, profile colorprofile.Profile) string {
+	variants := make([]string, 0, 2) //nolint:mnd
+	for _, isDark := range []bool{false, true} {
+		styles := opts.styles(cs(lipgloss.LightDark(isDark)))
+
+		var buf bytes.Buffer
+		w := &colorprofile.Writer{Forward: &buf, Profile: profile}
+		name := "light"
+		if isDark {
+			name = "dark"
+		}
+		_, _ = fmt.Fprintln(w, styles.Title.Render(name))
+		helpFn(sampleCmd(), w, styles)
+		opts.errHandler(w, styles, errors.New("something went wrong"))
+		variants = append(variants, strings.TrimRight(buf.String(), "\n"))
+	}
+
+	const gap = "    "
+	if lipgloss.Width(variants[0])+len(gap)+lipgloss.Width(variants[1]) > width() {
+		return lipgloss.JoinVertical(lipgloss.Left, variants...)
+	}
+	return lipgloss.JoinHorizontal(lipgloss.Top, variants[0], gap, variants[1])
+}
+
+func sampleCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "example [args]",
+		Short: "A sample program",
+		Example: `# Run it with some arguments:
+example --name=Carlos "quoted value"`,
+		Run: func(*cobra.Command, []string) {},
+	}
+	cmd.Flags().StringP("name", "n", "fang", "Your name")
+	cmd.AddCommand(&cobra.Command{
+		Use:   "sub",
+		Short: "A subcommand",
+		Run:   func(*cobra.Command, []string) {},
+	})
+	cmd.SetOut(io.Discard)
+	return cmd
+}
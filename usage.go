@@ -0,0 +1,172 @@
+package fang
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// WriteUsageSpec writes the spec of the given command tree in the KDL format
+// described in https://usage.jdx.dev, including aliases, examples, positional
+// arguments and the values declared with [MarkFlagEnum] or ValidArgs.
+//
+// Hidden commands and flags are left out. The format has no notion of command
+// groups, so these are left out as well.
+func WriteUsageSpec(w io.Writer, root *cobra.Command) error {
+	k := &kdlWriter{w: w}
+	k.node("name", kdlString(root.Name()))
+	k.node("bin", kdlString(root.Name()))
+	if root.Version != "" {
+		k.node("version", kdlString(root.Version))
+	}
+	if root.Short != "" {
+		k.node("about", kdlString(root.Short))
+	}
+	if root.Long != "" {
+		k.node("long_about", kdlString(root.Long))
+	}
+	k.command(root)
+	return k.err
+}
+
+type kdlWriter struct {
+	w      io.Writer
+	indent int
+	err    error
+}
+
+// node writes a node with the given arguments and properties.
+func (k *kdlWriter) node(name string, args ...string) {
+	k.block(name, nil, args...)
+}
+
+// block writes a node like [kdlWriter.node], calling children, if not nil,
+// to write its children.
+func (k *kdlWriter) block(name string, children func(), args ...string) {
+	if k.err != nil {
+		return
+	}
+	line := strings.Repeat("    ", k.indent) + strings.Join(append([]string{name}, args...), " ")
+	if children == nil {
+		_, k.err = fmt.Fprintln(k.w, line)
+		return
+	}
+	_, k.err = fmt.Fprintln(k.w, line+" {")
+	k.indent++
+	children()
+	k.indent--
+	if k.err == nil {
+		_, k.err = fmt.Fprintln(k.w, strings.Repeat("    ", k.indent)+"}")
+	}
+}
+
+// command writes the children of the node of the given command.
+func (k *kdlWriter) command(c *cobra.Command) {
+	for _, alias := range c.Aliases {
+		k.node("alias", kdlString(alias))
+	}
+	if c.HasParent() && c.Long != "" {
+		k.node("long_help", kdlString(c.Long))
+	}
+	if c.HasAvailableSubCommands() && !c.Runnable() {
+		k.node("subcommand_required", "#true")
+	}
+	if lines := exampleLines(c.Example); len(lines) > 0 {
+		k.node("example", kdlString(strings.Join(lines, "\n")))
+	}
+
+	persistent := c.PersistentFlags()
+	visitOwnFlags(c, func(f *pflag.Flag) {
+		if f.Hidden {
+			return
+		}
+		name := strings.Join(flagNames(f), " ")
+		value, usage := flagValueName(f)
+		if value != "" {
+			name += " <" + value + ">"
+		}
+		props := []string{kdlString(name)}
+		if usage != "" {
+			props = append(props, "help="+kdlString(usage))
+		}
+		if value != "" && hasDefault(f) && !isSensitive(f) {
+			props = append(props, "default="+kdlString(f.DefValue))
+		}
+		if isRequired(f) {
+			props = append(props, "required=#true")
+		}
+		if persistent.Lookup(f.Name) == f {
+			props = append(props, "global=#true")
+		}
+		switch {
+		case f.Value.Type() == "count":
+			props = append(props, "count=#true")
+		case isRepeatable(f):
+			props = append(props, "var=#true")
+		}
+		k.block("flag", k.choices(flagEnum(f)), props...)
+	})
+
+	choices := k.choices(argEnum(c))
+	for _, arg := range useArgs(c) {
+		// brackets and ellipsis tell whether it is required and variadic.
+		k.block("arg", choices, kdlString(argString(arg)))
+	}
+
+	for _, sc := range c.Commands() {
+		if !sc.IsAvailableCommand() {
+			continue
+		}
+		props := []string{kdlString(sc.Name())}
+		if sc.Short != "" {
+			props = append(props, "help="+kdlString(sc.Short))
+		}
+		k.block("cmd", func() { k.command(sc) }, props...)
+	}
+}
+
+// choices returns a function writing a choices node with the given values,
+// or nil if there are none.
+func (k *kdlWriter) choices(values []string) func() {
+	if len(values) == 0 {
+		return nil
+	}
+	return func() {
+		args := make([]string, 0, len(values))
+		for _, v := range values {
+			args = append(args, kdlString(v))
+		}
+		k.node("choices", args...)
+	}
+}
+
+// kdlString quotes the given string as a KDL string.
+func kdlString(s string) string {
+	var sb strings.Builder
+	sb.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			sb.WriteString(`\"`)
+		case '\\':
+			sb.WriteString(`\\`)
+		case '\n':
+			sb.WriteString(`\n`)
+		case '\r':
+			sb.WriteString(`\r`)
+		case '\t':
+			sb.WriteString(`\t`)
+		default:
+			if r < ' ' || r == 0x7f {
+				fmt.Fprintf(&sb, `\u{%x}`, r)
+				continue
+			}
+			sb.WriteRune(r)
+		}
+	}
+	sb.WriteByte('"')
+	return sb.String()
+}